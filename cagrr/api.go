@@ -0,0 +1,165 @@
+package cagrr
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const apiPrefix = "/api/clusters"
+
+// handleClusters serves progress API:
+// /api/clusters, /api/clusters/{c}, /api/clusters/{c}/keyspaces/{k},
+// /api/clusters/{c}/keyspaces/{k}/tables/{t} and /api/clusters/{c}/keyspaces/{k}/tables/{t}/fragments,
+// POST requests are control actions
+func (s *server) handleClusters(w http.ResponseWriter, req *http.Request) {
+	var parts []string
+	if path := strings.Trim(strings.TrimPrefix(req.URL.Path, apiPrefix), "/"); path != "" {
+		parts = strings.Split(path, "/")
+	}
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleControl(w, req, parts)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(parts) == 0 {
+		writeJSON(w, http.StatusOK, s.clusterList())
+		return
+	}
+	keys, rest := parseTarget(parts)
+	cluster := s.cluster(keys[0])
+	if cluster == nil {
+		http.Error(w, "Cluster not found", http.StatusNotFound)
+		return
+	}
+	switch {
+	case len(rest) == 0:
+		writeJSON(w, http.StatusOK, s.progress(cluster, len(keys) < 3, keys[1:]...))
+	case len(rest) == 1 && rest[0] == "fragments" && len(keys) == 3:
+		writeJSON(w, http.StatusOK, s.progress(cluster, true, keys[1:]...).Children)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *server) cluster(name string) *Cluster {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, c := range s.clusters {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// clusterSet lists clusters with distinct names, running cluster hides stopping one it replaces
+func (s *server) clusterSet() []*Cluster {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	seen := make(map[string]bool)
+	var result []*Cluster
+	for _, c := range s.clusters {
+		if !seen[c.Name] {
+			seen[c.Name] = true
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (s *server) clusterList() []*Progress {
+	clusters := s.clusterSet()
+	result := make([]*Progress, 0, len(clusters))
+	for _, c := range clusters {
+		result = append(result, s.progress(c, false))
+	}
+	return result
+}
+
+// progress of cluster or its keyspace or table, children are read from tracker
+func (s *server) progress(c *Cluster, children bool, keys ...string) *Progress {
+	path := append([]string{c.Name}, keys...)
+	result := &Progress{
+		Name:  path[len(path)-1],
+		Track: s.tracker.Read(path...),
+	}
+	if len(keys) == 0 {
+		result.Source = c.sourceFile()
+		s.mutex.RLock()
+		result.Last = s.last[c.Name]
+		s.mutex.RUnlock()
+	}
+	if !children {
+		return result
+	}
+
+	tracks := s.tracker.Children(path...)
+	names := make([]string, 0, len(tracks))
+	for name := range tracks {
+		names = append(names, name)
+	}
+	sort.Sort(byNumber(names))
+	for _, name := range names {
+		result.Children = append(result.Children, &Progress{Name: name, Track: tracks[name]})
+	}
+	return result
+}
+
+// remember latest statistics of cluster
+func (s *server) remember(stats *RepairStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.last[stats.Cluster] = stats
+}
+
+// parseTarget reads cluster and optional keyspaces/{k}, tables/{t} and fragments/{id} of path, the rest of path is returned
+func parseTarget(parts []string) ([]string, []string) {
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	keys := []string{parts[0]}
+	rest := parts[1:]
+	for _, level := range []string{"keyspaces", "tables", "fragments"} {
+		if len(rest) < 2 || rest[0] != level {
+			break
+		}
+		keys = append(keys, rest[1])
+		rest = rest[2:]
+	}
+	return keys, rest
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WithError(err).Warn("Response encoding error")
+	}
+}
+
+func (s byNumber) Len() int {
+	return len(s)
+}
+
+// Less orders fragments by their numeric IDs, other names alphabetically
+func (s byNumber) Less(i, j int) bool {
+	a, errA := strconv.Atoi(s[i])
+	b, errB := strconv.Atoi(s[j])
+	if errA == nil && errB == nil {
+		return a < b
+	}
+	return s[i] < s[j]
+}
+
+func (s byNumber) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
@@ -0,0 +1,78 @@
+package cagrr_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+var _ = Describe("API", func() {
+	var server Server
+	BeforeEach(func() {
+		tracker := NewTracker(memoryDB{}, NewRegulator(10))
+		tracker.StartCluster("c", 1)
+		tracker.StartKeyspace("c", "k", 1)
+		tracker.StartTable("c", "k", "t", 3)
+		for _, id := range []int{10, 2, 1} {
+			tracker.Start("c", "k", "t", id)
+		}
+		tracker.Complete("c", "k", "t", 2, false)
+		server = NewServer(tracker, []*Cluster{{Name: "c"}})
+	})
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+	decode := func(path string, value interface{}) {
+		recorder := get(http.MethodGet, path)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(json.Unmarshal(recorder.Body.Bytes(), value)).To(Succeed())
+	}
+
+	It("should list clusters", func() {
+		var clusters []*Progress
+		decode("/api/clusters", &clusters)
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Name).To(Equal("c"))
+		Expect(clusters[0].Track.Total).To(Equal(1))
+		Expect(clusters[0].Children).To(BeEmpty())
+	})
+
+	It("should return keyspaces of cluster and tables of keyspace", func() {
+		var cluster, keyspace Progress
+		decode("/api/clusters/c", &cluster)
+		Expect(cluster.Children).To(HaveLen(1))
+		Expect(cluster.Children[0].Name).To(Equal("k"))
+
+		decode("/api/clusters/c/keyspaces/k", &keyspace)
+		Expect(keyspace.Children).To(HaveLen(1))
+		Expect(keyspace.Children[0].Name).To(Equal("t"))
+		Expect(keyspace.Children[0].Track.Count).To(Equal(1))
+	})
+
+	It("should return fragments ordered by id", func() {
+		var fragments []*Progress
+		decode("/api/clusters/c/keyspaces/k/tables/t/fragments", &fragments)
+		Expect(fragments).To(HaveLen(3))
+		Expect(fragments[0].Name).To(Equal("1"))
+		Expect(fragments[1].Name).To(Equal("2"))
+		Expect(fragments[1].Track.Completed).To(BeTrue())
+		Expect(fragments[2].Name).To(Equal("10"))
+	})
+
+	It("should report unknown cluster and path", func() {
+		Expect(get(http.MethodGet, "/api/clusters/x").Code).To(Equal(http.StatusNotFound))
+		Expect(get(http.MethodGet, "/api/clusters/c/tables").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should allow only reads and control actions", func() {
+		Expect(get(http.MethodDelete, "/api/clusters").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
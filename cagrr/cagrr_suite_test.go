@@ -1,13 +1,31 @@
 package cagrr_test
 
 import (
+	"io/ioutil"
+	"os"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
 
 	"testing"
 )
 
+// testCluster is a minimal valid configuration, settings of cluster may be appended to it
+const testCluster = "clusters:\n  - name: test\n    host: localhost\n    port: 8080\n"
+
 func TestCagrr(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Cagrr Suite")
 }
+
+// readConfig reads configuration written to temporary file
+func readConfig(content string, overrides ...Override) (*Config, error) {
+	file, err := ioutil.TempFile("", "cagrr")
+	Expect(err).NotTo(HaveOccurred())
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(content)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(file.Close()).To(Succeed())
+	return ReadConfiguration(file.Name(), overrides...)
+}
@@ -3,21 +3,211 @@ package cagrr
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
+const (
+	allKeyspaces          = "*"
+	systemKeyspaces       = "system*"
+	defaultParallelism    = 1
+	defaultRepairsPerNode = 1
+	defaultRepairTimeout  = time.Hour
+	defaultMaxAttempts    = 3
+	defaultRetryBackoff   = time.Minute
+	reapInterval          = time.Minute
+	statusBuffer          = 1024
+	obtainTimeout         = 30 * time.Second
+	triggerTimeout        = 5 * time.Second
+	cancelBuffer          = 16
+	cancelTimeout         = 10 * time.Second
+)
+
+var cancelClient = &http.Client{Timeout: cancelTimeout}
+
+// Cancel running repairs of keyspace or table forwarding cancel to repair service,
+// their pending repairs of the current pass are dropped
+func (c *Cluster) Cancel(keyspace, table string) error {
+	select {
+	case c.cancelled() <- &scope{keyspace, table}:
+		return nil
+	default:
+		return errors.New("Too many cancel requests, try later")
+	}
+}
+
+// CancelRepair asks repair service to stop fragment repair
+func (c *Cluster) CancelRepair(repair *Repair) error {
+	url := c.address() + "/repair"
+	buf, _ := json.Marshal(repair)
+	req, err := http.NewRequest(http.MethodDelete, url, bytes.NewBuffer(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := cancelClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("Unexpected response status: %s", res.Status)
+	}
+	return nil
+}
+
+// IsPaused checks that cluster doesn't dispatch new repairs
+func (c *Cluster) IsPaused() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.paused
+}
+
+// Obtain repair status received from callback, callback is held while status queue is full.
+// Status of attempt which is not awaited anymore is ignored, false is returned then
+func (c *Cluster) Obtain(status *RepairStatus) bool {
+	if !c.claim(&status.Repair) {
+		log.WithFields(status.Repair).Debug("Status of stale repair attempt ignored")
+		return false
+	}
+	timer := time.NewTimer(obtainTimeout)
+	defer timer.Stop()
+	select {
+	case c.obtained() <- status:
+		return true
+	case <-timer.C:
+		c.await(&status.Repair)
+		log.WithFields(status.Repair).Warn(fmt.Sprintf("Status queue is full for %s, status dropped", obtainTimeout))
+		return false
+	}
+}
+
+// Pause dispatching of new repairs, running ones are still awaited
+func (c *Cluster) Pause() {
+	c.setPaused(true)
+	c.logger().Info("Cluster paused")
+}
+
+// Plan discovers cluster repairs without running them, estimate is based on average repair durations
+func (c *Cluster) Plan() *Plan {
+	plan := &Plan{Cluster: c.Name}
+	keyspaces, _ := c.keyspaces()
+
+	for _, k := range keyspaces {
+		for _, t := range k.Tables() {
+			threshold := c.threshold(t)
+			average := c.tracker.Read(c.Name, k.Name, t.Name).Average
+
+			for _, r := range t.Repairs() {
+				skip := c.tracker.IsCompleted(c.Name, k.Name, t.Name, r.ID, threshold)
+				plan.Items = append(plan.Items, &PlanItem{
+					Keyspace: k.Name,
+					Table:    t.Name,
+					ID:       r.ID,
+					Endpoint: r.Endpoint,
+					Start:    r.Start,
+					End:      r.End,
+					Skip:     skip,
+				})
+				plan.Total++
+				if skip {
+					plan.Skipped++
+				} else {
+					plan.Estimate += average
+				}
+			}
+		}
+	}
+	plan.Estimate /= time.Duration(c.parallelism())
+	return plan
+}
+
+// Reconfigure running cluster with new settings, they are applied between repairs
+func (c *Cluster) Reconfigure(settings *Cluster) {
+	updates := c.updated()
+	for {
+		select {
+		case updates <- settings:
+			return
+		default:
+			select {
+			case <-updates:
+			default:
+			}
+		}
+	}
+}
+
 // RegulateWith given rate limiter
 func (c *Cluster) RegulateWith(r Regulator) Scheduler {
 	c.regulator = r
 	return c
 }
 
+// Resume dispatching of repairs
+func (c *Cluster) Resume() {
+	c.setPaused(false)
+	c.logger().Info("Cluster resumed")
+}
+
+// RepairOnce runs repairs of keyspace or table right away ignoring schedule, windows and pause,
+// tokens restricts them to fragments overlapping "start:end" range, failed repairs are returned
+func (c *Cluster) RepairOnce(keyspace, table, tokens string, progress func(*Repair, string)) ([]*Repair, error) {
+	var start, end *big.Int
+	if tokens != "" {
+		var err error
+		if start, end, err = ParseTokenRange(tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	k := *c.keyspaceSettings(keyspace)
+	k.windows = nil
+	if table != "" {
+		k.include = nil
+		k.exclude = nil
+	}
+
+	keyspaces, _ := c.keyspacesOf([]*Keyspace{&k})
+	var pending []*Repair
+	for _, k := range keyspaces {
+		for _, t := range k.Tables() {
+			if table != "" && t.Name != table {
+				continue
+			}
+			for _, r := range t.Repairs() {
+				if start == nil || r.Overlaps(start, end) {
+					pending = append(pending, r)
+				}
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil, errors.New("No fragments to repair found")
+	}
+
+	c.manual = true
+	c.progress = progress
+	c.running = make(map[string]*Repair)
+	c.retrier = NewRetrier(c.maxAttempts(), c.retryBackoff())
+	c.reaper = time.NewTicker(c.reapInterval())
+	defer c.reaper.Stop()
+
+	c.restart(pending)
+	c.dispatch(pending)
+	c.report()
+	return c.poisoned, nil
+}
+
 // RunRepair runs fragment repair
 func (c *Cluster) RunRepair(repair *Repair) error {
-	url := fmt.Sprintf("http://%s:%d/repair", c.Host, c.Port)
+	url := c.address() + "/repair"
 
 	log.WithFields(repair).Info("Starting repair")
 
@@ -30,6 +220,7 @@ func (c *Cluster) RunRepair(repair *Repair) error {
 	if res != nil {
 		defer res.Body.Close()
 		if res.StatusCode != 200 {
+			err = fmt.Errorf("Unexpected response status: %s", res.Status)
 			log.WithError(err).WithFields(repair).Error("Fail to start repair")
 		}
 	}
@@ -40,39 +231,130 @@ func (c *Cluster) RunRepair(repair *Repair) error {
 
 // Schedule cluster repair
 func (c *Cluster) Schedule() {
+	c.running = make(map[string]*Repair)
+	c.retrier = NewRetrier(c.maxAttempts(), c.retryBackoff())
+	c.reaper = time.NewTicker(c.reapInterval())
+	defer c.reaper.Stop()
+	c.parseWindows()
+	c.refresh()
 
+	var requested *pass
 	for {
+		c.hold()
+		if c.isDone() {
+			log.WithFields(c).Info("Cluster stopped")
+			return
+		}
 		log.WithFields(c).Debug("Starting cluster")
 		keyspaces, total := c.keyspaces()
-		c.tracker.StartCluster(c.Name, total)
+		if requested != nil {
+			log.WithFields(c).Info(fmt.Sprintf("Starting requested pass of %s", requested))
+			if requested.force {
+				if err := c.tracker.Reset(requested.keys(c.Name)...); err != nil {
+					log.WithFields(c).WithError(err).Warn("Progress reset error")
+				}
+			}
+		}
+		if requested == nil || requested.keyspace == "" {
+			c.tracker.StartCluster(c.Name, total)
+		} else if scoped, err := requested.total(keyspaces); err == nil {
+			c.tracker.Restart(scoped, requested.keys(c.Name)...)
+		}
 
+		var pending []*Repair
 		for _, k := range keyspaces {
+			if !requested.covers(k.Name, "") {
+				continue
+			}
 			log.WithFields(k).Debug("Starting keyspace")
-			c.tracker.StartKeyspace(c.Name, k.Name, k.Total())
+			if requested == nil || requested.table == "" {
+				c.tracker.StartKeyspace(c.Name, k.Name, k.Total())
+			}
 
 			for _, t := range k.Tables() {
+				if !requested.covers(k.Name, t.Name) {
+					continue
+				}
 				log.WithFields(t).Debug("Starting table")
 				c.tracker.StartTable(c.Name, k.Name, t.Name, t.Total())
+				deadline, atRisk := c.deadline(k, t)
+				threshold := c.threshold(t)
 
 				for _, r := range t.Repairs() {
 
-					if c.tracker.IsCompleted(c.Name, k.Name, t.Name, r.ID, c.interval()) {
+					if c.tracker.IsCompleted(c.Name, k.Name, t.Name, r.ID, threshold) {
 						c.tracker.Skip(c.Name, k.Name, t.Name, r.ID)
+						c.publish(EventSkipped, r, "")
 						continue
 					}
-					c.tracker.Start(c.Name, k.Name, t.Name, r.ID)
-					err := c.RunRepair(r)
-					if err != nil {
-						c.tracker.TrackError(c.Name, k.Name, t.Name, r.ID)
-					}
+					r.atRisk = atRisk
+					r.deadline = deadline
+					r.weight = t.Weight
+					pending = append(pending, r)
 				}
 			}
 		}
+		sort.Stable(byPriority(pending))
+		c.dispatch(pending)
+		c.report()
+		requested = c.sleep(keyspaces)
+	}
+}
 
-		if !c.tracker.HasErrors(c.Name) {
-			c.sleep()
+// Status reads stored progress of cluster, keyspace or table together with their children
+func (c *Cluster) Status(keyspace, table string) []*Status {
+	interval := c.interval()
+	if keyspace == "" {
+		result := []*Status{c.status(interval)}
+		for _, k := range c.configured() {
+			result = append(result, c.status(inherit(interval, k.Interval), k.Name))
 		}
+		return result
+	}
+
+	k := c.keyspaceSettings(keyspace)
+	if table != "" {
+		t := &Table{Name: table}
+		k.Configure(t)
+		t.Inherit(k, interval, c.timeout())
+		return []*Status{c.status(t.RepairInterval(), k.Name, t.Name)}
 	}
+
+	result := []*Status{c.status(inherit(interval, k.Interval), k.Name)}
+	tables, err := c.tables(k.Name)
+	if err != nil {
+		log.WithError(err).Warn("Tables obtain error")
+	}
+	for _, t := range k.Filter(tables) {
+		k.Configure(t)
+		t.Inherit(k, interval, c.timeout())
+		result = append(result, c.status(t.RepairInterval(), k.Name, t.Name))
+	}
+	return result
+}
+
+// Trigger immediate pass of cluster, keyspace or table, force repairs fresh fragments too.
+// Pass is rejected while cluster is paused or the current pass is in progress
+func (c *Cluster) Trigger(keyspace, table string, force bool) error {
+	requested := &pass{scope{keyspace, table}, force, make(chan error, 1)}
+	timer := time.NewTimer(triggerTimeout)
+	defer timer.Stop()
+	select {
+	case c.triggered() <- requested:
+	case <-timer.C:
+		return errors.New("Scheduler is busy, try later")
+	}
+	if err := <-requested.reply; err != nil {
+		return err
+	}
+	c.logger().Info(fmt.Sprintf("Immediate pass of %s requested", requested))
+	return nil
+}
+
+// CallbackAt asks repair service to send statuses to given URL instead of its configured one
+func (c *Cluster) CallbackAt(url string) Scheduler {
+	c.callback = url
+	return c
 }
 
 // TrackIn given tracker
@@ -87,6 +369,242 @@ func (c *Cluster) Until(done chan bool) Scheduler {
 	return c
 }
 
+// address of repair service, it is safe to call outside of scheduler
+func (c *Cluster) address() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return fmt.Sprintf("http://%s:%d", c.Host, c.Port)
+}
+
+// admit requested pass when cluster is not paused and scope of pass is repaired
+func (c *Cluster) admit(requested *pass, keyspaces []*Keyspace) error {
+	if c.IsPaused() {
+		return errors.New("Cluster is paused")
+	}
+	_, err := requested.total(keyspaces)
+	return err
+}
+
+// apply new settings to running cluster, they are written under lock as API and probes read them
+func (c *Cluster) apply(settings *Cluster) {
+	c.mutex.Lock()
+	c.Interval = settings.Interval
+	c.Crontab = settings.Crontab
+	c.Parallelism = settings.Parallelism
+	c.MaxRepairsPerNode = settings.MaxRepairsPerNode
+	c.RepairTimeout = settings.RepairTimeout
+	c.MaxAttempts = settings.MaxAttempts
+	c.RetryBackoff = settings.RetryBackoff
+	c.FragmentSize = settings.FragmentSize
+	c.Windows = settings.Windows
+	c.Keyspaces = settings.Keyspaces
+	c.ExcludeKeyspaces = settings.ExcludeKeyspaces
+	c.Host = settings.Host
+	c.Port = settings.Port
+	c.cron = settings.cron
+	c.exclude = settings.exclude
+	c.path = settings.path
+	c.source = settings.source
+	c.mutex.Unlock()
+
+	c.retrier = NewRetrier(c.maxAttempts(), c.retryBackoff())
+	c.reaper.Stop()
+	c.reaper = time.NewTicker(c.reapInterval())
+	c.parseWindows()
+	log.WithFields(c).Info("Cluster reconfigured")
+}
+
+// await status of current repair attempt
+func (c *Cluster) await(r *Repair) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.awaited == nil {
+		c.awaited = make(map[string]int)
+	}
+	c.awaited[r.Key()] = r.Attempt
+}
+
+// cancel running and pending repairs of scope
+func (c *Cluster) cancel(s *scope) {
+	var pending []*Repair
+	for _, r := range c.pending {
+		if !s.covers(r.Keyspace, r.Table) {
+			pending = append(pending, r)
+		}
+	}
+	dropped := len(c.pending) - len(pending)
+	c.pending = pending
+
+	cancelled := 0
+	for key, r := range c.running {
+		if !s.covers(r.Keyspace, r.Table) || !c.claim(r) {
+			continue
+		}
+		delete(c.running, key)
+		if err := c.CancelRepair(r); err != nil {
+			log.WithError(err).WithFields(r).Warn("Fail to cancel repair")
+		}
+		c.tracker.TrackError(c.Name, r.Keyspace, r.Table, r.ID)
+		c.notify(r, "cancelled")
+		c.publish(EventCancelled, r, "")
+		cancelled++
+	}
+	log.WithFields(c).Info(fmt.Sprintf("Cancelled %d running repairs of %s, %d pending dropped", cancelled, s, dropped))
+}
+
+// claim awaited repair attempt, only the first of callback, reaper and cancel succeeds.
+// Status without attempt comes from repair service which doesn't echo it and claims the current attempt
+func (c *Cluster) claim(r *Repair) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	attempt, ok := c.awaited[r.Key()]
+	if !ok || (r.Attempt != 0 && attempt != r.Attempt) {
+		return false
+	}
+	r.Attempt = attempt
+	delete(c.awaited, r.Key())
+	return true
+}
+
+func (c *Cluster) cancelled() chan *scope {
+	c.channels()
+	return c.cancels
+}
+
+// configured keyspaces of cluster, discovers all of them for "*"
+func (c *Cluster) configured() []*Keyspace {
+	var result []*Keyspace
+	wildcard := false
+	for _, k := range c.Keyspaces {
+		if k.Name == allKeyspaces {
+			wildcard = true
+			continue
+		}
+		result = append(result, k)
+	}
+	if !wildcard {
+		return result
+	}
+
+	names, err := c.discover()
+	if err != nil {
+		log.WithError(err).Warn("Keyspaces obtain error")
+		return result
+	}
+	for _, name := range names {
+		if Matches(c.exclude, name) || c.keyspace(name) != nil {
+			continue
+		}
+		k := *c.keyspace(allKeyspaces)
+		k.Name = name
+		result = append(result, &k)
+	}
+	return result
+}
+
+// deadline of table repair and whether table is at risk to miss it, warns about such tables
+func (c *Cluster) deadline(k *Keyspace, t *Table) (time.Time, bool) {
+	if t.GcGrace <= 0 {
+		return time.Time{}, false
+	}
+	now := time.Now()
+	track := c.tracker.Read(c.Name, k.Name, t.Name)
+	last := track.Finished
+	if last.IsZero() {
+		last = track.Started
+	}
+	if last.IsZero() {
+		last = now
+	}
+	deadline := t.Deadline(last)
+
+	stats := &DeadlineStats{
+		Cluster:  c.Name,
+		Keyspace: k.Name,
+		Table:    t.Name,
+		GcGrace:  t.GracePeriod(),
+		Deadline: deadline,
+		Estimate: track.Estimate,
+		Margin:   deadline.Sub(now.Add(track.Estimate)),
+	}
+	atRisk := t.IsAtRisk(last, track.Estimate, now)
+	switch {
+	case deadline.Before(now):
+		log.WithFields(stats).Error("Table missed gc_grace_seconds deadline")
+	case atRisk:
+		log.WithFields(stats).Warn("Table is at risk of missing gc_grace_seconds deadline")
+	default:
+		log.WithFields(stats).Debug("Table deadline")
+	}
+	return deadline, atRisk
+}
+
+// delay returns time left until the earliest retry of pending repairs
+func (c *Cluster) delay() time.Duration {
+	result := reapInterval
+	now := time.Now()
+	for _, r := range c.pending {
+		left := r.retry.Sub(now)
+		if left > 0 && left < result {
+			result = left
+		}
+	}
+	return result
+}
+
+// discover names of all keyspaces in cluster
+func (c *Cluster) discover() ([]string, error) {
+	var result []string
+	url := c.address() + "/keyspaces"
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response, _ := ioutil.ReadAll(resp.Body)
+	err = json.Unmarshal(response, &result)
+	return result, err
+}
+
+// dispatch runs pending repairs keeping endpoints and cluster within limits
+func (c *Cluster) dispatch(pending []*Repair) {
+	c.pending = pending
+	c.poisoned = nil
+	for len(c.pending) > 0 || len(c.running) > 0 {
+		if len(c.pending) > 0 && c.isDone() {
+			log.WithFields(c).Info(fmt.Sprintf("Stopping cluster, %d repairs left pending, waiting for %d running", len(c.pending), len(c.running)))
+			c.pending = nil
+			continue
+		}
+		i := c.next()
+		if i < 0 {
+			c.wait()
+			continue
+		}
+		r := c.pending[i]
+		c.pending = append(c.pending[:i], c.pending[i+1:]...)
+		c.run(r)
+	}
+}
+
+// fail schedules retry of repair or poisons it when no attempts left
+func (c *Cluster) fail(r *Repair) {
+	r.attempts++
+	if c.retrier.IsPoisoned(r.attempts) {
+		c.notify(r, "poisoned")
+		c.poisoned = append(c.poisoned, r)
+		stats := c.tracker.Poison(c.Name, r.Keyspace, r.Table, r.ID)
+		log.WithFields(stats).Error(fmt.Sprintf("Repair poisoned after %d attempts", r.attempts))
+		return
+	}
+	backoff := c.retrier.Backoff(r.attempts)
+	r.retry = time.Now().Add(backoff)
+	c.pending = append(c.pending, r)
+	c.notify(r, fmt.Sprintf("failed, retry in %s", backoff))
+	log.WithFields(r).Warn(fmt.Sprintf("Repair failed, retry in %s", backoff))
+}
+
 func (c *Cluster) fragments(keyspace string, slices int) ([]*Fragment, error) {
 	tokens, err := c.tokens(keyspace, slices)
 	if err != nil {
@@ -105,7 +623,15 @@ func (c *Cluster) fragments(keyspace string, slices int) ([]*Fragment, error) {
 	return frags, nil
 }
 
+// interval of repair freshness: given one or period of cron schedule
 func (c *Cluster) interval() time.Duration {
+	if c.Interval == "" {
+		if c.cron != nil {
+			next := c.cron.Next(time.Now())
+			return c.cron.Next(next).Sub(next)
+		}
+		return week
+	}
 	duration, err := time.ParseDuration(c.Interval)
 	if err != nil {
 		log.WithFields(c).WithError(err).Warn("Duration parsing error")
@@ -115,18 +641,29 @@ func (c *Cluster) interval() time.Duration {
 }
 
 func (c *Cluster) keyspaces() ([]*Keyspace, int) {
+	return c.keyspacesOf(c.configured())
+}
+
+// keyspacesOf discovers tables and fragments of given keyspaces
+func (c *Cluster) keyspacesOf(configured []*Keyspace) ([]*Keyspace, int) {
 	total := 0
 	var result []*Keyspace
 
-	for _, k := range c.Keyspaces {
+	for _, k := range configured {
 		tables, err := c.tables(k.Name)
 		if err != nil {
 			log.WithError(err).Warn("Tables obtain error")
 			continue
 		}
+		tables = k.Filter(tables)
 		keyspaceTotal := 0
+		tokens := c.tokenCount(k.Name)
 
 		for _, t := range tables {
+			k.Configure(t)
+			t.SliceBy(c.FragmentSize, tokens)
+			t.Slices = k.slicesOf(t)
+			t.Inherit(k, c.interval(), c.timeout())
 			fragments, err := c.fragments(k.Name, t.Slices)
 			if err != nil {
 				log.WithError(err).Warn("Fragments obtain error")
@@ -139,9 +676,12 @@ func (c *Cluster) keyspaces() ([]*Keyspace, int) {
 					Start:    f.Start,
 					End:      f.End,
 					Endpoint: f.Endpoint,
+					Replicas: f.Replicas,
 					Cluster:  c.Name,
 					Keyspace: k.Name,
 					Table:    t.Name,
+					keyspace: k,
+					table:    t,
 				}
 				repairs = append(repairs, r)
 			}
@@ -159,9 +699,367 @@ func (c *Cluster) keyspaces() ([]*Keyspace, int) {
 	return result, total
 }
 
+// fits checks that repair doesn't exceed keyspace and table parallelism
+func (c *Cluster) fits(repair *Repair) bool {
+	keyspace, table := 0, 0
+	for _, r := range c.running {
+		if r.Keyspace == repair.Keyspace {
+			keyspace++
+			if r.Table == repair.Table {
+				table++
+			}
+		}
+	}
+	if limit := repair.keyspace.Parallelism; limit > 0 && keyspace >= limit {
+		return false
+	}
+	if limit := repair.table.Parallelism; limit > 0 && table >= limit {
+		return false
+	}
+	return true
+}
+
+// hold waits while cluster is paused
+func (c *Cluster) hold() {
+	if !c.IsPaused() {
+		return
+	}
+	log.WithFields(c).Info("Cluster is paused, waiting for resume")
+	for c.IsPaused() && !c.isDone() {
+		timer := time.NewTimer(reapInterval)
+		select {
+		case <-c.wakeups():
+		case <-c.done:
+		case s := <-c.cancelled():
+			c.cancel(s)
+		case requested := <-c.triggered():
+			requested.reply <- errors.New("Cluster is paused")
+		case settings := <-c.updated():
+			c.apply(settings)
+		case <-timer.C:
+			c.refresh()
+		}
+		timer.Stop()
+	}
+}
+
+// isDone checks that cluster is asked to stop
+func (c *Cluster) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// isOpen checks that repair is inside of keyspace or cluster maintenance windows
+func (c *Cluster) isOpen(r *Repair, now time.Time) bool {
+	k := c.keyspace(r.Keyspace)
+	if k == nil {
+		k = c.keyspace(allKeyspaces)
+	}
+	if k != nil && len(k.windows) > 0 {
+		return IsOpen(k.windows, now)
+	}
+	return IsOpen(c.windows, now)
+}
+
+// isIdle checks that every endpoint of repair has a free slot
+func (c *Cluster) isIdle(repair *Repair) bool {
+	limit := c.repairsPerNode()
+	for _, endpoint := range repair.Endpoints() {
+		busy := 0
+		for _, r := range c.running {
+			if r.Touches(endpoint) {
+				busy++
+			}
+		}
+		if busy >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+// keyspace configured explicitly by name
+func (c *Cluster) keyspace(name string) *Keyspace {
+	for _, k := range c.Keyspaces {
+		if k.Name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// keyspaceSettings returns configured keyspace or wildcard settings applied to it
+func (c *Cluster) keyspaceSettings(name string) *Keyspace {
+	if k := c.keyspace(name); k != nil {
+		return k
+	}
+	var k Keyspace
+	if wildcard := c.keyspace(allKeyspaces); wildcard != nil {
+		k = *wildcard
+	}
+	k.Name = name
+	return &k
+}
+
+// logger with fields of cluster, it is safe to call outside of scheduler
+func (c *Cluster) logger() Logger {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return log.WithFields(c)
+}
+
+func (c *Cluster) maxAttempts() int {
+	if c.MaxAttempts < 1 {
+		return defaultMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// next returns index of the first pending repair allowed to run, or -1, manual repairs ignore pause and windows
+func (c *Cluster) next() int {
+	if len(c.running) >= c.parallelism() || (!c.manual && c.IsPaused()) {
+		return -1
+	}
+	now := time.Now()
+	for i, r := range c.pending {
+		if r.retry.After(now) || (!c.manual && !c.isOpen(r, now)) {
+			continue
+		}
+		if c.isIdle(r) && c.fits(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextRun returns time of the next cluster pass or of the earliest repair due in tables with effective
+// interval shorter than cluster one. Pass follows schedule when it is set, otherwise interval after the previous pass is over
+func (c *Cluster) nextRun(over, now time.Time, keyspaces []*Keyspace) time.Time {
+	interval := c.interval()
+	next := over.Add(interval)
+	if c.cron != nil {
+		next = c.cron.Next(now)
+	}
+	for _, k := range keyspaces {
+		for _, t := range k.Tables() {
+			threshold := c.threshold(t)
+			if threshold >= interval {
+				continue
+			}
+			due := c.tracker.Read(c.Name, k.Name, t.Name).Finished.Add(threshold)
+			if !due.After(now) {
+				due = now.Add(threshold)
+			}
+			if due.Before(next) {
+				next = due
+			}
+		}
+	}
+	return next
+}
+
+func (c *Cluster) notify(r *Repair, event string) {
+	if c.progress != nil {
+		c.progress(r, event)
+	}
+}
+
+func (c *Cluster) obtained() chan *RepairStatus {
+	c.channels()
+	return c.statuses
+}
+
+func (c *Cluster) channels() {
+	c.once.Do(func() {
+		c.statuses = make(chan *RepairStatus, statusBuffer)
+		c.cancels = make(chan *scope, cancelBuffer)
+		c.triggers = make(chan *pass)
+		c.updates = make(chan *Cluster, 1)
+		c.wakeup = make(chan bool, 1)
+	})
+}
+
+func (c *Cluster) parallelism() int {
+	if c.Parallelism < 1 {
+		return defaultParallelism
+	}
+	return c.Parallelism
+}
+
+func (c *Cluster) release(status *RepairStatus) {
+	repair := &status.Repair
+	key := repair.Key()
+	r, ok := c.running[key]
+	if !ok || r.Attempt != repair.Attempt {
+		log.WithFields(repair).Debug("Status of unknown repair obtained")
+		return
+	}
+	delete(c.running, key)
+	if status.Type == "ERROR" {
+		c.fail(r)
+		return
+	}
+	c.notify(r, "completed")
+}
+
+// reap marks overdue repairs as timed out and frees their slots
+func (c *Cluster) reap() {
+	now := time.Now()
+	for key, r := range c.running {
+		timeout := r.table.RepairTimeout()
+		if now.Sub(r.started) < timeout || !c.claim(r) {
+			continue
+		}
+		delete(c.running, key)
+		stats := c.tracker.TrackTimeout(c.Name, r.Keyspace, r.Table, r.ID)
+		log.WithFields(stats).Warn(fmt.Sprintf("Repair timed out after %s", timeout))
+		c.publish(EventTimeout, r, fmt.Sprintf("Repair timed out after %s", timeout))
+		c.fail(r)
+	}
+}
+
+func (c *Cluster) parseWindows() {
+	c.windows = c.windowsOf(c.Windows)
+	for _, k := range c.Keyspaces {
+		k.windows = c.windowsOf(k.Windows)
+	}
+}
+
+// prepare parses schedule settings and patterns of cluster
+func (c *Cluster) prepare() error {
+	exclude, err := ParsePatterns(append([]string{systemKeyspaces}, c.ExcludeKeyspaces...))
+	if err != nil {
+		return fmt.Errorf("exclude_keyspaces: %s", err)
+	}
+	c.exclude = exclude
+	for _, k := range c.Keyspaces {
+		if err := k.prepare(); err != nil {
+			return err
+		}
+	}
+
+	if c.Interval != "" {
+		duration, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval: %s", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("interval should be positive: %s", c.Interval)
+		}
+	}
+	if c.Crontab != "" {
+		schedule, err := ParseCron(c.Crontab)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %s", err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("schedule never fires: %s", c.Crontab)
+		}
+		c.cron = schedule
+	}
+	return nil
+}
+
+func (c *Cluster) reapInterval() time.Duration {
+	timeout := c.timeout()
+	if timeout < reapInterval {
+		return timeout
+	}
+	return reapInterval
+}
+
+// refresh pause state persisted by tracker
+func (c *Cluster) refresh() {
+	paused := c.tracker.IsPaused(c.Name)
+	c.mutex.Lock()
+	c.paused = paused
+	c.mutex.Unlock()
+}
+
+// report repairs poisoned during the last pass
+func (c *Cluster) report() {
+	if len(c.poisoned) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(c.poisoned))
+	for _, r := range c.poisoned {
+		keys = append(keys, r.Key())
+	}
+	log.WithFields(c).Error(fmt.Sprintf("Cluster pass finished with %d poisoned repairs: %s", len(keys), strings.Join(keys, ", ")))
+}
+
+// restart tracks of tables, keyspaces and cluster of repairs, so that their totals are the given repairs
+func (c *Cluster) restart(repairs []*Repair) {
+	tables := make(map[scope]int)
+	keyspaces := make(map[string]int)
+	for _, r := range repairs {
+		tables[scope{r.Keyspace, r.Table}]++
+		keyspaces[r.Keyspace]++
+	}
+	for s, total := range tables {
+		c.tracker.Restart(total, c.Name, s.keyspace, s.table)
+	}
+	for keyspace, total := range keyspaces {
+		c.tracker.Restart(total, c.Name, keyspace)
+	}
+	c.tracker.Restart(len(repairs), c.Name)
+}
+
+func (c *Cluster) repairsPerNode() int {
+	if c.MaxRepairsPerNode < 1 {
+		return defaultRepairsPerNode
+	}
+	return c.MaxRepairsPerNode
+}
+
+func (c *Cluster) retryBackoff() time.Duration {
+	if c.RetryBackoff == "" {
+		return defaultRetryBackoff
+	}
+	duration, err := time.ParseDuration(c.RetryBackoff)
+	if err != nil || duration <= 0 {
+		log.WithFields(c).WithError(err).Warn("Retry backoff parsing error")
+		duration = defaultRetryBackoff
+	}
+	return duration
+}
+
+func (c *Cluster) triggered() chan *pass {
+	c.channels()
+	return c.triggers
+}
+
+func (c *Cluster) updated() chan *Cluster {
+	c.channels()
+	return c.updates
+}
+
+func (c *Cluster) run(r *Repair) {
+	c.tracker.Start(c.Name, r.Keyspace, r.Table, r.ID)
+	r.Attempt = r.attempts + 1
+	r.Callback = c.callback
+	c.await(r)
+	err := c.RunRepair(r)
+	if err != nil {
+		c.claim(r)
+		c.tracker.TrackError(c.Name, r.Keyspace, r.Table, r.ID)
+		c.publish(EventError, r, err.Error())
+		c.fail(r)
+		return
+	}
+	r.started = time.Now()
+	c.running[r.Key()] = r
+	c.notify(r, "started")
+	c.publish(EventDispatched, r, "")
+}
+
 func (c *Cluster) tables(keyspace string) ([]*Table, error) {
 	var result []*Table
-	url := fmt.Sprintf("http://%s:%d/tables/%s", c.Host, c.Port, keyspace)
+	url := fmt.Sprintf("%s/tables/%s", c.address(), keyspace)
 	log.Debug(fmt.Sprintf("URL: %s", url))
 
 	resp, err := http.Get(url)
@@ -182,9 +1080,89 @@ func (c *Cluster) tables(keyspace string) ([]*Table, error) {
 	return result, nil
 }
 
+func (c *Cluster) setPaused(paused bool) {
+	c.mutex.Lock()
+	c.paused = paused
+	c.mutex.Unlock()
+	if c.tracker != nil {
+		c.tracker.SetPaused(c.Name, paused)
+	}
+	select {
+	case c.wakeups() <- true:
+	default:
+	}
+}
+
+// sourceFile of cluster configuration, it is safe to call outside of scheduler
+func (c *Cluster) sourceFile() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.source
+}
+
+func (c *Cluster) status(interval time.Duration, keys ...string) *Status {
+	track := c.tracker.Read(append([]string{c.Name}, keys...)...)
+	status := &Status{
+		Cluster:   c.Name,
+		Percent:   track.Percent,
+		Completed: track.Count,
+		Total:     track.Total,
+		Errors:    track.Errors,
+		Average:   track.Average,
+		Estimate:  track.Estimate,
+		Finished:  track.Finished,
+		Interval:  interval,
+		Stale:     track.Finished.IsZero() || time.Since(track.Finished) > interval,
+	}
+	if len(keys) == 0 {
+		status.Source = c.sourceFile()
+	}
+	if len(keys) > 0 {
+		status.Keyspace = keys[0]
+	}
+	if len(keys) > 1 {
+		status.Table = keys[1]
+	}
+	return status
+}
+
+// threshold of fragment freshness: effective interval limited by table gc_grace_seconds
+func (c *Cluster) threshold(t *Table) time.Duration {
+	interval := t.RepairInterval()
+	if t.GcGrace > 0 && t.GracePeriod() < interval {
+		return t.GracePeriod()
+	}
+	return interval
+}
+
+func (c *Cluster) timeout() time.Duration {
+	if c.RepairTimeout == "" {
+		return defaultRepairTimeout
+	}
+	duration, err := time.ParseDuration(c.RepairTimeout)
+	if err != nil || duration <= 0 {
+		log.WithFields(c).WithError(err).Warn("Repair timeout parsing error")
+		duration = defaultRepairTimeout
+	}
+	return duration
+}
+
+// tokenCount returns number of token ranges when tables are sliced by size
+func (c *Cluster) tokenCount(keyspace string) int {
+	if c.FragmentSize <= 0 {
+		return 0
+	}
+	tokens, err := c.tokens(keyspace, 1)
+	if err != nil {
+		log.WithError(err).Warn("Token count obtain error")
+		return 0
+	}
+	return len(tokens)
+}
+
 func (c *Cluster) tokens(keyspace string, slices int) (TokenSet, error) {
 	var tokens TokenSet
-	url := fmt.Sprintf("http://%s:%d/ring/%s/%d", c.Host, c.Port, keyspace, slices)
+	url := fmt.Sprintf("%s/ring/%s/%d", c.address(), keyspace, slices)
 	res, err := http.Get(url)
 	if err != nil {
 		log.WithError(err).Error("Failed to obtain ring description")
@@ -198,8 +1176,76 @@ func (c *Cluster) tokens(keyspace string, slices int) (TokenSet, error) {
 	return tokens, err
 }
 
-func (c *Cluster) sleep() {
-	duration := c.interval()
-	log.WithFields(c).Debug(fmt.Sprintf("Cluster scheduled. Going to sleep for: %s", duration))
-	time.Sleep(duration)
+// wait for repair status, time to reap lost repairs, time to retry or control event
+func (c *Cluster) wait() {
+	timer := time.NewTimer(c.delay())
+	defer timer.Stop()
+
+	done := c.done
+	if c.isDone() {
+		done = nil
+	}
+
+	select {
+	case status := <-c.obtained():
+		c.release(status)
+	case <-c.reaper.C:
+		c.reap()
+		c.refresh()
+	case <-c.wakeups():
+	case s := <-c.cancelled():
+		c.cancel(s)
+	case requested := <-c.triggered():
+		requested.reply <- errors.New("Current pass is in progress, try later")
+	case settings := <-c.updated():
+		c.apply(settings)
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+func (c *Cluster) wakeups() chan bool {
+	c.channels()
+	return c.wakeup
+}
+
+func (c *Cluster) windowsOf(specs []string) []Window {
+	windows, err := ParseWindows(specs)
+	if err != nil {
+		log.WithFields(c).WithError(err).Error("Window parsing error, repairs are suspended")
+		return []Window{closedWindow{}}
+	}
+	return windows
+}
+
+// sleep until the next scheduled pass or until pass is requested
+func (c *Cluster) sleep(keyspaces []*Keyspace) *pass {
+	over := time.Now()
+	for {
+		now := time.Now()
+		next := c.nextRun(over, now, keyspaces)
+		duration := next.Sub(now)
+		log.WithFields(c).Debug(fmt.Sprintf("Cluster scheduled. Going to sleep until %s for: %s", next.Format(timeFormat), duration))
+
+		timer := time.NewTimer(duration)
+		select {
+		case <-timer.C:
+			return nil
+		case <-c.done:
+			timer.Stop()
+			return nil
+		case requested := <-c.triggered():
+			timer.Stop()
+			err := c.admit(requested, keyspaces)
+			requested.reply <- err
+			if err == nil {
+				return requested
+			}
+		case s := <-c.cancelled():
+			c.cancel(s)
+		case settings := <-c.updated():
+			timer.Stop()
+			c.apply(settings)
+		}
+	}
 }
@@ -0,0 +1,299 @@
+package cagrr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+// fakeCajrr is a repair service of keyspace "k", every table has fragments 1..n on their own endpoints
+type fakeCajrr struct {
+	*httptest.Server
+	cancels   chan *Repair
+	fragments int
+	repairs   chan *Repair
+	rings     chan string
+	tables    string
+}
+
+func newFakeCajrr(tables string, fragments int) *fakeCajrr {
+	f := &fakeCajrr{
+		cancels:   make(chan *Repair, 100),
+		fragments: fragments,
+		repairs:   make(chan *Repair, 100),
+		rings:     make(chan string, 100),
+		tables:    tables,
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// cluster "c" using repair service with given cluster settings
+func (f *fakeCajrr) cluster(settings string) *Cluster {
+	host, port, _ := net.SplitHostPort(f.Listener.Addr().String())
+	config, err := readConfig(fmt.Sprintf("clusters:\n  - name: c\n    host: %s\n    port: %s\n    keyspaces:\n      - name: k\n", host, port) + settings)
+	Expect(err).NotTo(HaveOccurred())
+	return config.Clusters[0]
+}
+
+func (f *fakeCajrr) serve(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == "/keyspaces":
+		w.Write([]byte(`["k"]`))
+	case strings.HasPrefix(req.URL.Path, "/tables/"):
+		w.Write([]byte(f.tables))
+	case strings.HasPrefix(req.URL.Path, "/ring/"):
+		select {
+		case f.rings <- req.URL.Path:
+		default:
+		}
+		var ranges []string
+		for i := 1; i <= f.fragments; i++ {
+			ranges = append(ranges, fmt.Sprintf(`{"id": %d, "Endpoint": "10.0.0.%d", "Start": "%d", "End": "%d"}`, i, i, i*100, i*100+100))
+		}
+		fmt.Fprintf(w, `[{"id": "1", "Ranges": [%s]}]`, strings.Join(ranges, ","))
+	case req.URL.Path == "/repair":
+		var r Repair
+		json.NewDecoder(req.Body).Decode(&r)
+		if req.Method == http.MethodDelete {
+			f.cancels <- &r
+		} else {
+			f.repairs <- &r
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// callback posts status of repair to server the way repair service does
+func callback(server Server, r *Repair, kind string) {
+	body, _ := json.Marshal(&RepairStatus{Repair: *r, Type: kind})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/status", bytes.NewReader(body)))
+}
+
+var _ = Describe("Cluster", func() {
+	var (
+		cajrr      *fakeCajrr
+		cluster    *Cluster
+		db         memoryDB
+		dispatched []*Repair
+		done       chan bool
+		server     Server
+		stopped    chan bool
+		tracker    Tracker
+	)
+	BeforeEach(func() {
+		NewLogger("panic", "")
+		cajrr = newFakeCajrr(`[{"name": "t"}]`, 3)
+		db = memoryDB{}
+		tracker = NewTracker(db, NewRegulator(10))
+		dispatched = nil
+		done = make(chan bool)
+		stopped = make(chan bool)
+	})
+	AfterEach(func() {
+		close(done)
+		for _, r := range dispatched {
+			callback(server, r, "COMPLETE")
+		}
+		Eventually(stopped).Should(BeClosed())
+		cajrr.Close()
+	})
+
+	schedule := func(settings string) {
+		cluster = cajrr.cluster(settings)
+		server = NewServer(tracker, []*Cluster{cluster})
+		go func(scheduler Scheduler) {
+			defer close(stopped)
+			scheduler.Schedule()
+		}(cluster.TrackIn(tracker).Until(done))
+	}
+	receive := func() *Repair {
+		var r *Repair
+		Eventually(cajrr.repairs, "3s").Should(Receive(&r))
+		dispatched = append(dispatched, r)
+		return r
+	}
+
+	It("should keep in-flight repairs within parallelism", func() {
+		schedule("    parallelism: 2\n")
+		first := receive()
+		receive()
+		Consistently(cajrr.repairs, "200ms").ShouldNot(Receive())
+
+		callback(server, first, "COMPLETE")
+		Expect(receive().ID).To(Equal(3))
+	})
+
+	It("should release slot of failed repair and retry it", func() {
+		cajrr.fragments = 1
+		schedule("    retry_backoff: 10ms\n")
+		first := receive()
+		Consistently(cajrr.repairs, "200ms").ShouldNot(Receive())
+
+		callback(server, first, "ERROR")
+		Expect(receive().ID).To(Equal(first.ID))
+		Expect(tracker.Read("c", "k", "t").Errors).To(Equal(1))
+	})
+
+	It("should ignore late status of timed out attempt", func() {
+		cajrr.fragments = 1
+		schedule("    repair_timeout: 500ms\n    retry_backoff: 10ms\n")
+		first := receive()
+		second := receive()
+		Expect(first.Attempt).To(Equal(1))
+		Expect(second.Attempt).To(Equal(2))
+
+		callback(server, first, "COMPLETE")
+		Expect(tracker.Read("c", "k", "t").Count).To(Equal(0))
+		callback(server, second, "COMPLETE")
+		Expect(tracker.Read("c", "k", "t").Count).To(Equal(1))
+	})
+
+	It("should take status without attempt as status of the current attempt", func() {
+		cajrr.fragments = 1
+		schedule("    retry_backoff: 10ms\n")
+		first := receive()
+		first.Attempt = 0
+		callback(server, first, "ERROR")
+		second := receive()
+		Expect(second.Attempt).To(Equal(2))
+
+		second.Attempt = 0
+		callback(server, second, "COMPLETE")
+		Expect(tracker.Read("c", "k", "t").Count).To(Equal(1))
+		Expect(tracker.Read("c", "k", "t").Errors).To(Equal(1))
+	})
+
+	It("should repair tables with the earliest deadline first", func() {
+		cajrr.fragments = 1
+		cajrr.tables = `[{"name": "unlimited"}, {"name": "late", "gcGraceSeconds": 7200}, {"name": "early", "gcGraceSeconds": 3600}]`
+		schedule("    parallelism: 1\n")
+		for _, table := range []string{"early", "late", "unlimited"} {
+			r := receive()
+			Expect(r.Table).To(Equal(table))
+			callback(server, r, "COMPLETE")
+		}
+	})
+
+	It("should prefer tables at risk, then heavier tables, then the earliest deadline", func() {
+		finished := time.Now().Add(-2 * time.Hour)
+		track, _ := json.Marshal(&Track{Completed: true, Started: finished, Finished: finished})
+		db.WriteValue("repairs", "c/k/risky", track)
+		cajrr.fragments = 1
+		cajrr.tables = `[{"name": "plain", "gcGraceSeconds": 3600}, {"name": "heavy", "weight": 2, "gcGraceSeconds": 7200}, {"name": "risky", "gcGraceSeconds": 3600}]`
+		schedule("    parallelism: 1\n")
+		for _, table := range []string{"risky", "heavy", "plain"} {
+			r := receive()
+			Expect(r.Table).To(Equal(table))
+			callback(server, r, "COMPLETE")
+		}
+	})
+
+	It("should start the next pass interval after the previous one is over", func() {
+		cajrr.fragments = 1
+		schedule("    interval: 1s\n")
+		first := receive()
+		callback(server, first, "COMPLETE")
+		completed := time.Now()
+		Expect(receive().ID).To(Equal(first.ID))
+		Expect(time.Since(completed)).To(BeNumerically(">=", time.Second))
+	})
+
+	It("should wake up for table with interval shorter than cluster one", func() {
+		cajrr.fragments = 1
+		schedule("        tables:\n          - name: t\n            interval: 1s\n    interval: 1h\n")
+		first := receive()
+		callback(server, first, "COMPLETE")
+		Expect(receive().ID).To(Equal(first.ID))
+	})
+
+	It("should hold paused cluster until resume", func() {
+		cluster = cajrr.cluster("")
+		cluster.TrackIn(tracker).Pause()
+		server = NewServer(tracker, []*Cluster{cluster})
+		go func(scheduler Scheduler) {
+			defer close(stopped)
+			scheduler.Schedule()
+		}(cluster.Until(done))
+		Consistently(cajrr.repairs, "200ms").ShouldNot(Receive())
+
+		cluster.Resume()
+		receive()
+	})
+
+	It("should repair once ignoring pause and keep configured keyspaces", func() {
+		cajrr.fragments = 1
+		cluster = cajrr.cluster("")
+		keyspace := cluster.Keyspaces[0]
+		cluster.TrackIn(tracker).CallbackAt("http://localhost:8889/status").Pause()
+		server = NewServer(tracker, []*Cluster{cluster})
+		var failed []*Repair
+		go func() {
+			defer close(stopped)
+			failed, _ = cluster.RepairOnce("k", "t", "", nil)
+		}()
+
+		r := receive()
+		Expect(r.Callback).To(Equal("http://localhost:8889/status"))
+		callback(server, r, "COMPLETE")
+		Eventually(stopped).Should(BeClosed())
+		Expect(failed).To(BeEmpty())
+		Expect(cluster.Keyspaces).To(HaveLen(1))
+		Expect(cluster.Keyspaces[0]).To(BeIdenticalTo(keyspace))
+	})
+
+	It("should slice table by configured size", func() {
+		cluster = cajrr.cluster("        tables:\n          - name: t\n            size: 1000\n    fragment_size: 100\n")
+		cluster.TrackIn(tracker)
+		close(stopped)
+		cluster.Plan()
+		Expect(cajrr.rings).To(Receive(Equal("/ring/k/1")))
+		Expect(cajrr.rings).To(Receive(Equal("/ring/k/10")))
+	})
+
+	It("should count repair once in tracks of the previous pass", func() {
+		tracker.StartCluster("c", 2)
+		tracker.StartKeyspace("c", "k", 2)
+		tracker.StartTable("c", "k", "t", 2)
+		for id := 1; id <= 2; id++ {
+			tracker.Start("c", "k", "t", id)
+			tracker.Complete("c", "k", "t", id, false)
+		}
+		cluster = cajrr.cluster("")
+		cluster.TrackIn(tracker)
+		server = NewServer(tracker, []*Cluster{cluster})
+		go func() {
+			defer close(stopped)
+			cluster.RepairOnce("k", "t", "100:200", nil)
+		}()
+
+		callback(server, receive(), "COMPLETE")
+		Eventually(stopped).Should(BeClosed())
+		for _, keys := range [][]string{{"c"}, {"c", "k"}, {"c", "k", "t"}} {
+			track := tracker.Read(keys...)
+			Expect(track.Count).To(Equal(1))
+			Expect(track.Total).To(Equal(1))
+			Expect(track.Percent).To(BeNumerically("==", 100))
+		}
+	})
+
+	It("should ignore status of unknown repair", func() {
+		schedule("")
+		first := receive()
+		callback(server, &Repair{ID: 2, Cluster: "c", Keyspace: "k", Table: "t"}, "COMPLETE")
+		Consistently(cajrr.repairs, "200ms").ShouldNot(Receive())
+
+		callback(server, first, "COMPLETE")
+		Expect(receive().ID).To(Equal(2))
+	})
+})
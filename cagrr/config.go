@@ -1,22 +1,340 @@
 package cagrr
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v2"
 )
 
-// ReadConfiguration parses yaml configuration file
-func ReadConfiguration(filename string) (*Config, error) {
-	var c Config
-	filename, _ = filepath.Abs(filename)
-	yamlFile, err := ioutil.ReadFile(filename)
+const (
+	defaultBuffer     = 10
+	defaultConsulHost = "localhost:8500"
+	defaultSource     = "default"
+	envPrefix         = "CAGRR_"
+)
+
+// EnvOverrides reads CAGRR_CONSUL_HOST, CAGRR_BUFFER, CAGRR_CLUSTER_<NAME>_HOST and CAGRR_CLUSTER_<NAME>_PORT variables,
+// other variables are ignored
+func EnvOverrides(environ []string) []Override {
+	var result []Override
+	for _, variable := range environ {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], envPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(parts[0], envPrefix)
+		var key string
+		switch {
+		case name == "CONSUL_HOST" || name == "BUFFER":
+			key = strings.ToLower(name)
+		case strings.HasPrefix(name, "CLUSTER_") && strings.HasSuffix(name, "_HOST"):
+			key = "cluster." + strings.TrimSuffix(name[len("CLUSTER_"):], "_HOST") + ".host"
+		case strings.HasPrefix(name, "CLUSTER_") && strings.HasSuffix(name, "_PORT"):
+			key = "cluster." + strings.TrimSuffix(name[len("CLUSTER_"):], "_PORT") + ".port"
+		default:
+			continue
+		}
+		result = append(result, Override{
+			Key:    key,
+			Value:  parts[1],
+			Source: "env " + parts[0],
+		})
+	}
+	sort.Sort(byKey(result))
+	return result
+}
 
+// ReadConfiguration parses yaml configuration file or every *.yml and *.yaml file of directory together with
+// included files over defaults, then applies environment and given overrides,
+// all problems found are returned as ConfigErrors
+func ReadConfiguration(filename string, overrides ...Override) (*Config, error) {
+	c := Config{
+		BufferLength: defaultBuffer,
+		ConsulHost:   defaultConsulHost,
+		files:        make(map[string]map[string]int),
+		sources:      make(map[string]string),
+	}
+	files, err := configFiles(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(yamlFile, &c)
-	return &c, err
+	var errs ConfigErrors
+	for len(files) > 0 {
+		file := files[0]
+		files = files[1:]
+		if _, ok := c.files[file]; ok {
+			continue
+		}
+		includes, fileErrs, err := c.merge(file)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, fileErrs...)
+		files = append(files, includes...)
+	}
+
+	var env []Override
+	for _, o := range EnvOverrides(os.Environ()) {
+		if !c.configures(o) {
+			log.Warn(fmt.Sprintf("Cluster of %s is not configured, variable is ignored", o.Source))
+			continue
+		}
+		env = append(env, o)
+	}
+	for _, o := range append(env, overrides...) {
+		if overrideErr := c.override(o); overrideErr != nil {
+			errs = append(errs, &ConfigError{File: o.Source, Message: overrideErr.Error()})
+		}
+	}
+	if errs = append(errs, c.validate()...); len(errs) > 0 {
+		sort.Stable(errs)
+		return &c, errs
+	}
+	return &c, c.prepare()
+}
+
+// Files configuration was read from
+func (c *Config) Files() []string {
+	result := make([]string, 0, len(c.files))
+	for file := range c.files {
+		result = append(result, file)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Settings lists every effective configuration value with its source: default, file line, environment or flag
+func (c *Config) Settings() []*Setting {
+	var result []*Setting
+	out, _ := yaml.Marshal(c)
+	var tree yaml.MapSlice
+	yaml.Unmarshal(out, &tree)
+	c.flatten("", tree, &result)
+	return result
+}
+
+func (c *Config) flatten(path string, value interface{}, result *[]*Setting) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			key := fmt.Sprintf("%v", item.Key)
+			if path != "" {
+				key = path + "." + key
+			}
+			c.flatten(key, item.Value, result)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			c.setting(path, "[]", result)
+		}
+		for i, item := range v {
+			c.flatten(fmt.Sprintf("%s[%d]", path, i), item, result)
+		}
+	case nil:
+		c.setting(path, "", result)
+	default:
+		c.setting(path, fmt.Sprintf("%v", v), result)
+	}
+}
+
+// merge clusters and settings of file into configuration, included files are returned
+func (c *Config) merge(filename string) ([]string, ConfigErrors, error) {
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var part Config
+	err = yaml.UnmarshalStrict(source, &part)
+	errs := ValidateConfiguration(filename, source, &part, err)
+	lines := locate(source)
+	c.files[filename] = lines
+	v := &validator{file: filename, lines: lines}
+
+	for _, key := range []string{"consul_host", "buffer"} {
+		if _, ok := lines[key]; !ok {
+			continue
+		}
+		if previous, ok := c.sources[key]; ok {
+			v.add(key, "already set at %s", previous)
+			continue
+		}
+		c.sources[key] = fmt.Sprintf("%s:%d", filename, lines[key])
+		if key == "consul_host" {
+			c.ConsulHost = part.ConsulHost
+		} else {
+			c.BufferLength = part.BufferLength
+		}
+	}
+
+	offsets := map[string]int{"clusters[": len(c.Clusters), "metrics[": len(c.Metrics)}
+	for path, line := range lines {
+		for list, offset := range offsets {
+			if strings.HasPrefix(path, list) {
+				c.sources[shiftItem(path, offset)] = fmt.Sprintf("%s:%d", filename, line)
+			}
+		}
+	}
+	c.Metrics = append(c.Metrics, part.Metrics...)
+	for i, cluster := range part.Clusters {
+		cluster.source = filename
+		cluster.path = fmt.Sprintf("clusters[%d]", i)
+		c.Clusters = append(c.Clusters, cluster)
+	}
+
+	var includes []string
+	for i, pattern := range part.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filename), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			v.add(fmt.Sprintf("include[%d]", i), "%s", err)
+			continue
+		}
+		includes = append(includes, matches...)
+	}
+	c.Include = append(c.Include, part.Include...)
+	return includes, append(errs, v.errors...), nil
+}
+
+// configures checks that override of cluster setting has its cluster configured
+func (c *Config) configures(o Override) bool {
+	parts := strings.Split(o.Key, ".")
+	if len(parts) != 3 || parts[0] != "cluster" {
+		return true
+	}
+	for _, cluster := range c.Clusters {
+		if cluster.Name == parts[1] || envName(cluster.Name) == parts[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) override(o Override) error {
+	switch o.Key {
+	case "consul_host":
+		c.ConsulHost = o.Value
+		c.sources["consul_host"] = o.Source
+		return nil
+	case "buffer":
+		buffer, err := strconv.Atoi(o.Value)
+		if err != nil {
+			return fmt.Errorf("invalid buffer: %s", err)
+		}
+		c.BufferLength = buffer
+		c.sources["buffer"] = o.Source
+		return nil
+	}
+
+	parts := strings.Split(o.Key, ".")
+	if len(parts) != 3 || parts[0] != "cluster" {
+		return fmt.Errorf("unknown setting %s", o.Key)
+	}
+	for i, cluster := range c.Clusters {
+		if cluster.Name != parts[1] && envName(cluster.Name) != parts[1] {
+			continue
+		}
+		path := fmt.Sprintf("clusters[%d].%s", i, parts[2])
+		switch parts[2] {
+		case "host":
+			cluster.Host = o.Value
+		case "port":
+			port, err := strconv.Atoi(o.Value)
+			if err != nil {
+				return fmt.Errorf("invalid port of cluster %s: %s", cluster.Name, err)
+			}
+			cluster.Port = port
+		default:
+			return fmt.Errorf("unknown setting %s of cluster %s", parts[2], cluster.Name)
+		}
+		c.sources[path] = o.Source
+		return nil
+	}
+	return fmt.Errorf("cluster %s is not configured", parts[1])
+}
+
+func (c *Config) prepare() error {
+	for _, cluster := range c.Clusters {
+		if err := cluster.prepare(); err != nil {
+			return fmt.Errorf("Cluster %s of %s: %s", cluster.Name, cluster.source, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) setting(path, value string, result *[]*Setting) {
+	source, ok := c.sources[path]
+	if !ok {
+		source = defaultSource
+	}
+	*result = append(*result, &Setting{Path: path, Value: value, Source: source})
+}
+
+// configFiles returns absolute name of file or all yaml files of directory
+func configFiles(filename string) ([]string, error) {
+	filename, _ = filepath.Abs(filename)
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{filename}, nil
+	}
+
+	var result []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(filename, pattern))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matches...)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("No configuration files found in %s", filename)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// envName of cluster used in environment variables: upper case with underscores
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+func (s byKey) Len() int {
+	return len(s)
+}
+
+func (s byKey) Less(i, j int) bool {
+	return s[i].Key < s[j].Key
+}
+
+func (s byKey) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// shiftItem moves path of cluster or sink inside of file to its place in merged configuration
+func shiftItem(path string, offset int) string {
+	start := strings.Index(path, "[")
+	end := strings.Index(path, "]")
+	index, err := strconv.Atoi(path[start+1 : end])
+	if err != nil {
+		return path
+	}
+	return fmt.Sprintf("%s[%d]%s", path[:start], index+offset, path[end+1:])
 }
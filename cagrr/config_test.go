@@ -1,6 +1,10 @@
 package cagrr_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	. "github.com/skbkontur/cagrr/cagrr"
 
 	. "github.com/onsi/ginkgo"
@@ -24,4 +28,219 @@ var _ = Describe("Config", func() {
 			Expect(config).To(BeNil())
 		})
 	})
+	Context("All keyspaces", func() {
+		var config *Config
+		BeforeEach(func() {
+			config, _ = readConfig(testCluster + "    keyspaces: \"*\"\n    exclude_keyspaces: [\"stats_*\"]\n")
+		})
+		It("Should read wildcard keyspace", func() {
+			Expect(config.Clusters[0].Keyspaces).To(HaveLen(1))
+			Expect(config.Clusters[0].Keyspaces[0].Name).To(Equal("*"))
+		})
+	})
+	Context("Invalid table pattern", func() {
+		var err error
+		BeforeEach(func() {
+			_, err = readConfig(testCluster + "    keyspaces:\n      - name: test\n        exclude_tables: [\"/(/\"]\n")
+		})
+		It("Should return error", func() {
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Invalid schedule", func() {
+		var err error
+		BeforeEach(func() {
+			_, err = readConfig(testCluster + "    schedule: 0 25 * * *\n")
+		})
+		It("Should return error", func() {
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Schedule which never fires", func() {
+		It("Should return error", func() {
+			_, err := readConfig(testCluster + "    schedule: 0 0 31 2 *\n")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Schedule in zone with half-hour offset", func() {
+		It("Should be accepted", func() {
+			_, err := readConfig(testCluster + "    schedule: 0 3 * * * Asia/Kolkata\n")
+			Expect(err).To(BeNil())
+		})
+	})
+	Context("Invalid configuration", func() {
+		var errs ConfigErrors
+		BeforeEach(func() {
+			_, err := readConfig(`consul_host: localhost
+clusters:
+  - name: test
+    host: localhost
+    port: 8080
+    intervl: 1h
+  - name: test
+    port: 70000
+    interval: soon
+    keyspaces:
+      - name: test
+        tables:
+          - name: test
+            weight: heavy
+`)
+			errs, _ = err.(ConfigErrors)
+		})
+		lines := func() []int {
+			var result []int
+			for _, e := range errs {
+				result = append(result, e.Line)
+			}
+			return result
+		}
+		It("Should return all errors ordered by line", func() {
+			Expect(lines()).To(Equal([]int{6, 7, 7, 8, 9, 14}))
+		})
+		It("Should report unknown field", func() {
+			Expect(errs[0].Path).To(Equal("clusters[0].intervl"))
+		})
+		It("Should report duplicate cluster name", func() {
+			Expect(errs[1].Message).To(MatchRegexp("first defined at .+:3$"))
+		})
+		It("Should report missing host", func() {
+			Expect(errs[2].Message).To(Equal("host is required"))
+		})
+	})
+	Context("Configuration with flow style and anchors", func() {
+		It("Should report errors at their lines", func() {
+			_, err := readConfig(`clusters:
+  - &base {name: a, host: localhost, port: 8080, intervl: 1h}
+  - <<: *base
+    name: b
+    port: 70000
+`)
+			errs, _ := err.(ConfigErrors)
+			Expect(errs).NotTo(BeEmpty())
+			Expect(errs[0].Line).To(Equal(2))
+			Expect(errs[0].Path).To(Equal("clusters[0].intervl"))
+			Expect(errs[len(errs)-1].Line).To(Equal(5))
+			Expect(errs[len(errs)-1].Path).To(Equal("clusters[1].port"))
+		})
+	})
+	Context("Metrics sinks", func() {
+		var errs ConfigErrors
+		BeforeEach(func() {
+			_, err := readConfig(`metrics:
+  - type: statsd
+    address: localhost:8125
+  - type: influx
+    address: localhost
+    prefix: "{cluster}.{table}.{node}"
+clusters:
+  - name: test
+    host: localhost
+    port: 8080
+`)
+			errs, _ = err.(ConfigErrors)
+		})
+		It("Should report invalid sinks only", func() {
+			Expect(errs).To(HaveLen(4))
+			Expect(errs[0].Path).To(Equal("metrics[1].type"))
+			Expect(errs[1].Path).To(Equal("metrics[1].address"))
+			Expect(errs[2].Message).To(Equal("unknown placeholder {node}"))
+			Expect(errs[3].Line).To(Equal(6))
+		})
+	})
+	Context("Overrides", func() {
+		var config *Config
+		var err error
+		BeforeEach(func() {
+			os.Setenv("CAGRR_CLUSTER_TEST_PORT", "9090")
+			os.Setenv("CAGRR_HOME", "/opt/cagrr")
+			defer os.Unsetenv("CAGRR_CLUSTER_TEST_PORT")
+			defer os.Unsetenv("CAGRR_HOME")
+			config, err = readConfig(testCluster, Override{Key: "cluster.test.host", Value: "cajrr", Source: "flag"})
+		})
+		source := func(path string) string {
+			for _, s := range config.Settings() {
+				if s.Path == path {
+					return s.Source
+				}
+			}
+			return ""
+		}
+		It("Should apply defaults", func() {
+			Expect(err).To(BeNil())
+			Expect(config.BufferLength).To(Equal(10))
+			Expect(source("buffer")).To(Equal("default"))
+		})
+		It("Should apply environment", func() {
+			Expect(config.Clusters[0].Port).To(Equal(9090))
+			Expect(source("clusters[0].port")).To(Equal("env CAGRR_CLUSTER_TEST_PORT"))
+		})
+		It("Should apply flags", func() {
+			Expect(config.Clusters[0].Host).To(Equal("cajrr"))
+			Expect(source("clusters[0].host")).To(Equal("flag"))
+		})
+		It("Should keep file values", func() {
+			Expect(source("clusters[0].name")).To(HaveSuffix(":2"))
+		})
+		It("Should ignore environment of unknown cluster", func() {
+			NewLogger("panic", "")
+			os.Setenv("CAGRR_CLUSTER_OTHER_HOST", "cajrr")
+			defer os.Unsetenv("CAGRR_CLUSTER_OTHER_HOST")
+			_, err := readConfig(testCluster)
+			Expect(err).To(BeNil())
+		})
+		It("Should reject flags of unknown cluster", func() {
+			_, err := readConfig(testCluster, Override{Key: "cluster.other.host", Value: "cajrr", Source: "flag"})
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Directory", func() {
+		var dir string
+		write := func(name, content string) {
+			os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		}
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "cagrr")
+			write("main.yml", "consul_host: consul\ninclude: [\"teams/*.yml\"]\nclusters:\n  - name: main\n    host: localhost\n    port: 8080\n")
+			write("teams/a.yml", "clusters:\n  - name: a\n    host: localhost\n    port: 8080\n")
+		})
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+		It("Should merge clusters of all files", func() {
+			config, err := ReadConfiguration(dir)
+			Expect(err).To(BeNil())
+			Expect(config.Clusters).To(HaveLen(2))
+			Expect(config.Files()).To(HaveLen(2))
+			Expect(config.ConsulHost).To(Equal("consul"))
+		})
+		It("Should report source file of cluster", func() {
+			config, _ := ReadConfiguration(filepath.Join(dir, "main.yml"))
+			config.Clusters[1].TrackIn(NewTracker(memoryDB{}, NewRegulator(10)))
+			Expect(config.Clusters[1].Status("", "")[0].Source).To(Equal(filepath.Join(dir, "teams/a.yml")))
+			source := ""
+			for _, s := range config.Settings() {
+				if s.Path == "clusters[1].name" {
+					source = s.Source
+				}
+			}
+			Expect(source).To(Equal(filepath.Join(dir, "teams/a.yml") + ":2"))
+		})
+		It("Should detect duplicate cluster names across files", func() {
+			write("teams/b.yml", "clusters:\n  - name: a\n    host: localhost\n    port: 8080\n")
+			_, err := ReadConfiguration(dir)
+			errs, ok := err.(ConfigErrors)
+			Expect(ok).To(BeTrue())
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].File).To(Equal(filepath.Join(dir, "teams/b.yml")))
+			Expect(errs[0].Line).To(Equal(2))
+			Expect(errs[0].Message).To(ContainSubstring(filepath.Join(dir, "teams/a.yml") + ":2"))
+		})
+		It("Should detect conflicting settings", func() {
+			write("teams/b.yml", "consul_host: other\n")
+			_, err := ReadConfiguration(dir)
+			Expect(err).NotTo(BeNil())
+		})
+	})
 })
@@ -1,6 +1,7 @@
 package cagrr
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/hashicorp/consul/api"
@@ -33,6 +34,37 @@ func (r *consulDB) Delete(table, key string) {
 	r.db.KV().Delete(consulKey, nil)
 }
 
+func (r *consulDB) DeleteTree(table, prefix string) error {
+	consulPrefix := strings.Join([]string{table, prefix}, "/")
+	_, err := r.db.KV().DeleteTree(consulPrefix, nil)
+	return err
+}
+
+func (r *consulDB) ListKeys(table, prefix string) ([]string, error) {
+	consulPrefix := strings.Join([]string{table, prefix}, "/")
+	keys, _, err := r.db.KV().Keys(consulPrefix, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, strings.TrimPrefix(key, table+"/"))
+	}
+	return result, nil
+}
+
+// Ping checks that consul agent answers and its cluster has a leader
+func (r *consulDB) Ping() error {
+	leader, err := r.db.Status().Leader()
+	if err != nil {
+		return err
+	}
+	if leader == "" {
+		return errors.New("No consul cluster leader")
+	}
+	return nil
+}
+
 func (r *consulDB) ReadValue(table, key string) []byte {
 
 	// Get a handle to the KV API
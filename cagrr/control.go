@@ -0,0 +1,199 @@
+package cagrr
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditTo writes control actions to given writer as JSON lines, they are logged anyway
+func (s *server) AuditTo(w io.Writer) Server {
+	s.audit = w
+	return s
+}
+
+// AuthorizeBy enables control API for requests with "Authorization: Bearer <token>" header
+func (s *server) AuthorizeBy(token string) Server {
+	s.token = token
+	return s
+}
+
+// handleControl performs POST /api/clusters/{c}[/keyspaces/{k}[/tables/{t}[/fragments/{id}]]]/{action}
+func (s *server) handleControl(w http.ResponseWriter, req *http.Request, parts []string) {
+	entry := &AuditEntry{
+		Time:   time.Now(),
+		Remote: req.RemoteAddr,
+		Force:  req.URL.Query().Get("force") == "true",
+	}
+	keys, rest := parseTarget(parts)
+	if len(rest) == 1 {
+		entry.Action = rest[0]
+	}
+	fields := []*string{&entry.Cluster, &entry.Keyspace, &entry.Table, &entry.Fragment}
+	for i, key := range keys {
+		*fields[i] = key
+	}
+
+	status, err := s.authorize(req)
+	if err == nil {
+		status, err = s.control(entry, keys)
+	}
+	entry.Status = status
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.record(entry)
+
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cagrr"`)
+	}
+	writeJSON(w, status, entry)
+}
+
+func (s *server) authorize(req *http.Request) (int, error) {
+	if s.token == "" {
+		return http.StatusForbidden, errors.New("Control API is disabled")
+	}
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return http.StatusUnauthorized, errors.New("Bearer token required")
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return http.StatusUnauthorized, errors.New("Invalid token")
+	}
+	return http.StatusOK, nil
+}
+
+// control performs action of entry on cluster, keyspace, table or fragment given by keys
+func (s *server) control(entry *AuditEntry, keys []string) (int, error) {
+	if entry.Action == "" {
+		return http.StatusNotFound, errors.New("Unknown action")
+	}
+	cluster := s.cluster(entry.Cluster)
+	if cluster == nil {
+		return http.StatusNotFound, errors.New("Cluster not found")
+	}
+
+	switch entry.Action {
+	case "pause", "resume":
+		if len(keys) > 1 {
+			return http.StatusBadRequest, fmt.Errorf("Only whole cluster can %s", entry.Action)
+		}
+		if entry.Action == "pause" {
+			cluster.Pause()
+		} else {
+			cluster.Resume()
+		}
+		return http.StatusOK, nil
+	case "repair":
+		if entry.Fragment != "" {
+			return http.StatusBadRequest, errors.New("Use repair command to repair token range")
+		}
+		if err := cluster.Trigger(entry.Keyspace, entry.Table, entry.Force); err != nil {
+			return http.StatusConflict, err
+		}
+		return http.StatusAccepted, nil
+	case "cancel":
+		if entry.Fragment != "" {
+			return http.StatusBadRequest, errors.New("Single fragment can't be cancelled")
+		}
+		if err := cluster.Cancel(entry.Keyspace, entry.Table); err != nil {
+			return http.StatusServiceUnavailable, err
+		}
+		return http.StatusAccepted, nil
+	case "reset":
+		if entry.Fragment != "" {
+			if _, err := strconv.Atoi(entry.Fragment); err != nil {
+				return http.StatusBadRequest, fmt.Errorf("Invalid fragment id %q", entry.Fragment)
+			}
+		}
+		if err := s.tracker.Reset(keys...); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusOK, nil
+	}
+	return http.StatusNotFound, errors.New("Unknown action")
+}
+
+// record control action in audit log
+func (s *server) record(entry *AuditEntry) {
+	logged := log.WithFields(entry)
+	if entry.Error != "" {
+		logged.Warn("Control action rejected")
+	} else {
+		logged.Info("Control action performed")
+	}
+	if s.audit == nil {
+		return
+	}
+
+	line, _ := json.Marshal(entry)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.audit.Write(append(line, '\n')); err != nil {
+		log.WithError(err).Error("Audit log write error")
+	}
+}
+
+// covers checks that requested pass includes keyspace and table, nil pass includes everything
+func (p *pass) covers(keyspace, table string) bool {
+	return p == nil || p.scope.covers(keyspace, table)
+}
+
+// covers checks that keyspace and table are inside of scope, empty table matches any table of keyspace
+func (s scope) covers(keyspace, table string) bool {
+	if s.keyspace != "" && s.keyspace != keyspace {
+		return false
+	}
+	return s.table == "" || table == "" || s.table == table
+}
+
+// total number of fragments inside of scope, error is returned when scope is not repaired
+func (s scope) total(keyspaces []*Keyspace) (int, error) {
+	total := 0
+	found := false
+	for _, k := range keyspaces {
+		if s.keyspace != "" && s.keyspace != k.Name {
+			continue
+		}
+		for _, t := range k.Tables() {
+			if s.table == "" || s.table == t.Name {
+				total += t.Total()
+				found = true
+			}
+		}
+	}
+	if !found && s.keyspace != "" {
+		return 0, fmt.Errorf("Nothing to repair in %s", s)
+	}
+	return total, nil
+}
+
+// keys of scope in tracker
+func (s scope) keys(cluster string) []string {
+	keys := []string{cluster}
+	if s.keyspace != "" {
+		keys = append(keys, s.keyspace)
+		if s.table != "" {
+			keys = append(keys, s.table)
+		}
+	}
+	return keys
+}
+
+func (s scope) String() string {
+	switch {
+	case s.table != "":
+		return fmt.Sprintf("table %s.%s", s.keyspace, s.table)
+	case s.keyspace != "":
+		return fmt.Sprintf("keyspace %s", s.keyspace)
+	}
+	return "cluster"
+}
@@ -0,0 +1,163 @@
+package cagrr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+var _ = Describe("Control API", func() {
+	var (
+		audit   *bytes.Buffer
+		cluster *Cluster
+		server  Server
+		tracker Tracker
+	)
+	BeforeEach(func() {
+		NewLogger("panic", "")
+		tracker = NewTracker(memoryDB{}, NewRegulator(10))
+		tracker.StartCluster("c", 1)
+		tracker.StartKeyspace("c", "k", 1)
+		tracker.StartTable("c", "k", "t", 1)
+		tracker.Start("c", "k", "t", 1)
+		tracker.Complete("c", "k", "t", 1, false)
+
+		cluster = &Cluster{Name: "c"}
+		cluster.TrackIn(tracker)
+		audit = &bytes.Buffer{}
+		server = NewServer(tracker, []*Cluster{cluster}).AuthorizeBy("secret").AuditTo(audit)
+	})
+
+	post := func(path, token string) (int, *AuditEntry) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+		var entry AuditEntry
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &entry)).To(Succeed())
+		return recorder.Code, &entry
+	}
+
+	It("should be disabled without token", func() {
+		server = NewServer(tracker, []*Cluster{cluster})
+		code, _ := post("/api/clusters/c/pause", "secret")
+		Expect(code).To(Equal(http.StatusForbidden))
+		Expect(cluster.IsPaused()).To(BeFalse())
+	})
+
+	It("should reject invalid token", func() {
+		code, entry := post("/api/clusters/c/pause", "wrong")
+		Expect(code).To(Equal(http.StatusUnauthorized))
+		Expect(entry.Error).To(Equal("Invalid token"))
+		Expect(cluster.IsPaused()).To(BeFalse())
+	})
+
+	It("should pause and resume cluster", func() {
+		code, _ := post("/api/clusters/c/pause", "secret")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(cluster.IsPaused()).To(BeTrue())
+		Expect(tracker.IsPaused("c")).To(BeTrue())
+
+		code, _ = post("/api/clusters/c/resume", "secret")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(cluster.IsPaused()).To(BeFalse())
+
+		code, _ = post("/api/clusters/c/keyspaces/k/pause", "secret")
+		Expect(code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should trigger pass of repaired scope while cluster sleeps", func() {
+		cajrr := newFakeCajrr(`[{"name": "t"}]`, 1)
+		defer cajrr.Close()
+		cluster = cajrr.cluster("")
+		server = NewServer(tracker, []*Cluster{cluster}).AuthorizeBy("secret")
+		done := make(chan bool)
+		stopped := make(chan bool)
+		go func(scheduler Scheduler) {
+			defer close(stopped)
+			scheduler.Schedule()
+		}(cluster.TrackIn(tracker).Until(done))
+		defer func() {
+			close(done)
+			Eventually(stopped).Should(BeClosed())
+		}()
+		trigger := func(path string) int {
+			code, _ := post(path, "secret")
+			return code
+		}
+		rejection := func(path string) string {
+			code, entry := post(path, "secret")
+			Expect(code).To(Equal(http.StatusConflict))
+			return entry.Error
+		}
+
+		before := time.Now()
+		Eventually(func() int { return trigger("/api/clusters/c/keyspaces/k/tables/t/repair") }).Should(Equal(http.StatusAccepted))
+		Eventually(func() time.Time { return tracker.Read("c", "k", "t").Started }).Should(BeTemporally(">", before))
+		Eventually(func() int { return tracker.Read("c", "k", "t").Count }).Should(Equal(1))
+		Expect(tracker.Read("c").Count).To(Equal(1))
+
+		Eventually(func() string { return rejection("/api/clusters/c/keyspaces/x/repair") }).Should(Equal("Nothing to repair in keyspace x"))
+
+		Expect(trigger("/api/clusters/c/keyspaces/k/tables/t/repair?force=true")).To(Equal(http.StatusAccepted))
+		var repair *Repair
+		Eventually(cajrr.repairs, "3s").Should(Receive(&repair))
+		Expect(rejection("/api/clusters/c/repair")).To(Equal("Current pass is in progress, try later"))
+
+		callback(server, repair, "COMPLETE")
+		Expect(tracker.Read("c").Count).To(Equal(1))
+		Expect(tracker.Read("c").Percent).To(BeEquivalentTo(100))
+
+		Expect(trigger("/api/clusters/c/pause")).To(Equal(http.StatusOK))
+		Eventually(func() string { return rejection("/api/clusters/c/repair") }).Should(Equal("Cluster is paused"))
+	})
+
+	It("should accept cancel of keyspace", func() {
+		code, entry := post("/api/clusters/c/keyspaces/k/cancel", "secret")
+		Expect(code).To(Equal(http.StatusAccepted))
+		Expect(entry.Keyspace).To(Equal("k"))
+	})
+
+	It("should reset progress of fragment", func() {
+		code, _ := post("/api/clusters/c/keyspaces/k/tables/t/fragments/x/reset", "secret")
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		code, _ = post("/api/clusters/c/keyspaces/k/tables/t/fragments/1/reset", "secret")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(tracker.Read("c", "k", "t", "1").IsNew()).To(BeTrue())
+		Expect(tracker.Read("c", "k", "t").IsNew()).To(BeFalse())
+	})
+
+	It("should report unknown cluster and action", func() {
+		code, _ := post("/api/clusters/x/pause", "secret")
+		Expect(code).To(Equal(http.StatusNotFound))
+		code, _ = post("/api/clusters/c/explode", "secret")
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should audit performed and rejected actions", func() {
+		post("/api/clusters/c/pause", "wrong")
+		post("/api/clusters/c/keyspaces/k/tables/t/reset", "secret")
+
+		lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		var rejected, performed AuditEntry
+		Expect(json.Unmarshal([]byte(lines[0]), &rejected)).To(Succeed())
+		Expect(json.Unmarshal([]byte(lines[1]), &performed)).To(Succeed())
+		Expect(rejected.Action).To(Equal("pause"))
+		Expect(rejected.Status).To(Equal(http.StatusUnauthorized))
+		Expect(performed.Action).To(Equal("reset"))
+		Expect(performed.Table).To(Equal("t"))
+		Expect(performed.Status).To(Equal(http.StatusOK))
+		Expect(performed.Error).To(BeEmpty())
+	})
+})
@@ -0,0 +1,209 @@
+package cagrr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cronMinutes  = cronField{0, 59, nil}
+	cronHours    = cronField{0, 23, nil}
+	cronDays     = cronField{1, 31, nil}
+	cronMonths   = cronField{1, 12, monthNames}
+	cronWeekdays = cronField{0, 7, dayNames}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+
+	dayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+)
+
+// ParseCron parses standard five-field cron expression with optional trailing time zone,
+// e.g. "0 22 * * Mon-Fri Europe/Moscow" or "@daily"
+func ParseCron(spec string) (Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Empty cron expression")
+	}
+
+	location := time.Local
+	if descriptor, ok := cronDescriptors[strings.ToLower(fields[0])]; ok {
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("Unexpected fields in cron expression %q", spec)
+		}
+		if len(fields) == 2 {
+			loc, err := time.LoadLocation(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			location = loc
+		}
+		fields = strings.Fields(descriptor)
+	}
+
+	switch len(fields) {
+	case 5:
+	case 6:
+		loc, err := time.LoadLocation(fields[5])
+		if err != nil {
+			return nil, err
+		}
+		location = loc
+		fields = fields[:5]
+	default:
+		return nil, fmt.Errorf("Cron expression %q should have 5 fields", spec)
+	}
+
+	c := &cron{location: location}
+	var err error
+	if c.minutes, err = cronMinutes.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if c.hours, err = cronHours.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if c.days, err = cronDays.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if c.months, err = cronMonths.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if c.weekdays, err = cronWeekdays.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1
+	}
+	c.anyDay = fields[2] == "*" || fields[2] == "?"
+	c.anyWeekday = fields[4] == "*" || fields[4] == "?"
+	return c, nil
+}
+
+// Contains checks that time matches cron expression with a minute precision
+func (c *cron) Contains(t time.Time) bool {
+	t = t.In(c.location)
+	return has(c.minutes, t.Minute()) &&
+		has(c.hours, t.Hour()) &&
+		has(c.months, int(t.Month())) &&
+		c.matchDay(t)
+}
+
+// Next returns the first matching time after given one, zero time if there is no such
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.In(c.location).Add(time.Minute).Truncate(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(c.months, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !has(c.hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+			continue
+		}
+		if !has(c.minutes, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	day := has(c.days, t.Day())
+	weekday := has(c.weekdays, int(t.Weekday()))
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func (f cronField) parse(expr string) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(expr, ",") {
+		bits, err := f.parsePart(part)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid cron field %q: %s", expr, err)
+		}
+		result |= bits
+	}
+	return result, nil
+}
+
+func (f cronField) parsePart(part string) (uint64, error) {
+	step := 1
+	if i := strings.Index(part, "/"); i >= 0 {
+		var err error
+		step, err = strconv.Atoi(part[i+1:])
+		if err != nil || step < 1 {
+			return 0, fmt.Errorf("bad step %q", part[i+1:])
+		}
+		part = part[:i]
+	}
+
+	start, end := f.min, f.max
+	if part != "*" && part != "?" {
+		bounds := strings.SplitN(part, "-", 2)
+		var err error
+		if start, err = f.value(bounds[0]); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(bounds) == 2 {
+			if end, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+		} else if step > 1 {
+			end = f.max
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("range %d-%d is reversed", start, end)
+	}
+
+	var result uint64
+	for i := start; i <= end; i += step {
+		result |= 1 << uint(i)
+	}
+	return result, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func has(bits uint64, i int) bool {
+	return bits&(1<<uint(i)) != 0
+}
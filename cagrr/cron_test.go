@@ -0,0 +1,93 @@
+package cagrr_test
+
+import (
+	"time"
+
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron", func() {
+	at := func(value string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", value)
+		return t
+	}
+
+	It("should match steps", func() {
+		cron, err := ParseCron("*/15 * * * * UTC")
+		Expect(err).To(BeNil())
+		Expect(cron.Contains(at("2017-03-07 10:45"))).To(BeTrue())
+		Expect(cron.Contains(at("2017-03-07 10:46"))).To(BeFalse())
+	})
+
+	It("should match names", func() {
+		cron, err := ParseCron("0 3 * Mar Tue UTC")
+		Expect(err).To(BeNil())
+		Expect(cron.Contains(at("2017-03-07 03:00"))).To(BeTrue())
+		Expect(cron.Contains(at("2017-03-08 03:00"))).To(BeFalse())
+	})
+
+	It("should match either day of month or weekday", func() {
+		cron, err := ParseCron("0 0 1 * Sun UTC")
+		Expect(err).To(BeNil())
+		Expect(cron.Contains(at("2017-03-01 00:00"))).To(BeTrue())
+		Expect(cron.Contains(at("2017-03-05 00:00"))).To(BeTrue())
+		Expect(cron.Contains(at("2017-03-06 00:00"))).To(BeFalse())
+	})
+
+	It("should treat 7 as Sunday", func() {
+		cron, err := ParseCron("0 0 * * 7 UTC")
+		Expect(err).To(BeNil())
+		Expect(cron.Contains(at("2017-03-05 00:00"))).To(BeTrue())
+	})
+
+	It("should expand descriptors", func() {
+		cron, err := ParseCron("@daily UTC")
+		Expect(err).To(BeNil())
+		Expect(cron.Contains(at("2017-03-05 00:00"))).To(BeTrue())
+		Expect(cron.Contains(at("2017-03-05 00:01"))).To(BeFalse())
+	})
+
+	Context("next time", func() {
+		It("should find next minute of the same hour", func() {
+			cron, _ := ParseCron("30 * * * * UTC")
+			Expect(cron.Next(at("2017-03-07 10:15"))).To(BeTemporally("==", at("2017-03-07 10:30")))
+		})
+		It("should be strictly after given time", func() {
+			cron, _ := ParseCron("30 * * * * UTC")
+			Expect(cron.Next(at("2017-03-07 10:30"))).To(BeTemporally("==", at("2017-03-07 11:30")))
+		})
+		It("should cross month boundaries", func() {
+			cron, _ := ParseCron("0 2 1 * * UTC")
+			Expect(cron.Next(at("2017-03-07 10:15"))).To(BeTemporally("==", at("2017-04-01 02:00")))
+		})
+		It("should find weekday", func() {
+			cron, _ := ParseCron("0 22 * * Sat UTC")
+			Expect(cron.Next(at("2017-03-07 10:15"))).To(BeTemporally("==", at("2017-03-11 22:00")))
+		})
+		It("should find hour in zone with half-hour offset", func() {
+			cron, _ := ParseCron("0 3 * * * Asia/Kolkata")
+			Expect(cron.Next(at("2017-03-07 10:15"))).To(BeTemporally("==", at("2017-03-07 21:30")))
+		})
+		It("should find hour in zone with quarter-hour offset", func() {
+			cron, _ := ParseCron("0 3 * * * Asia/Kathmandu")
+			Expect(cron.Next(at("2017-03-07 10:15"))).To(BeTemporally("==", at("2017-03-07 21:15")))
+		})
+		It("should return zero time for impossible date", func() {
+			cron, _ := ParseCron("0 0 31 2 * UTC")
+			Expect(cron.Next(at("2017-03-07 10:15")).IsZero()).To(BeTrue())
+		})
+	})
+
+	It("should fail on wrong field count", func() {
+		_, err := ParseCron("0 0 * *")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should fail on out of range value", func() {
+		_, err := ParseCron("60 0 * * *")
+		Expect(err).NotTo(BeNil())
+	})
+})
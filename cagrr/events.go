@@ -0,0 +1,163 @@
+package cagrr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Repair lifecycle event types
+const (
+	EventDispatched       = "dispatched"
+	EventComplete         = "complete"
+	EventError            = "error"
+	EventTimeout          = "timeout"
+	EventSkipped          = "skipped"
+	EventCancelled        = "cancelled"
+	EventClusterCompleted = "cluster_completed"
+
+	eventBuffer    = 256
+	keepaliveDelay = 15 * time.Second
+)
+
+// NewPublisher creates publisher of repair events, slow subscribers lose events which don't fit in their buffer
+func NewPublisher() Publisher {
+	return &publisher{
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+// Publish event to every subscriber interested in it
+func (p *publisher) Publish(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for s := range p.subscribers {
+		if !s.matches(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe to events of given clusters and keyspaces, empty lists match everything.
+// Returned function cancels subscription
+func (p *publisher) Subscribe(clusters, keyspaces []string) (<-chan *Event, func()) {
+	s := &subscriber{
+		clusters:  clusters,
+		events:    make(chan *Event, eventBuffer),
+		keyspaces: keyspaces,
+	}
+	p.mutex.Lock()
+	p.subscribers[s] = true
+	p.mutex.Unlock()
+
+	return s.events, func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		delete(p.subscribers, s)
+	}
+}
+
+// PublishTo publishes lifecycle events of repairs to given publisher
+func (c *Cluster) PublishTo(p Publisher) Scheduler {
+	c.events = p
+	return c
+}
+
+// publish event of cluster repair
+func (c *Cluster) publish(kind string, r *Repair, message string) {
+	if c.events == nil {
+		return
+	}
+	c.events.Publish(&Event{
+		Type:     kind,
+		Cluster:  c.Name,
+		Keyspace: r.Keyspace,
+		Table:    r.Table,
+		ID:       r.ID,
+		Message:  message,
+	})
+}
+
+// PublishTo publishes received repair statuses to given publisher and streams them at /api/events
+func (s *server) PublishTo(p Publisher) Server {
+	s.events = p
+	return s
+}
+
+// handleEvents streams events as Server-Sent Events, ?cluster= and ?keyspace= filter them
+func (s *server) handleEvents(w http.ResponseWriter, req *http.Request) {
+	if s.events == nil {
+		http.NotFound(w, req)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	query := req.URL.Query()
+	events, cancel := s.events.Subscribe(query["cluster"], query["keyspace"])
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(keepaliveDelay)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e := <-events:
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-req.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// publish event of repair status
+func (s *server) publish(kind string, repair *Repair, message string, stats *RepairStats) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(&Event{
+		Type:     kind,
+		Cluster:  repair.Cluster,
+		Keyspace: repair.Keyspace,
+		Table:    repair.Table,
+		ID:       repair.ID,
+		Message:  message,
+		Stats:    stats,
+	})
+}
+
+func (s *subscriber) matches(e *Event) bool {
+	return contains(s.clusters, e.Cluster) && contains(s.keyspaces, e.Keyspace)
+}
+
+// contains checks that value is in list, empty list contains everything
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
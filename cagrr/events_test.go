@@ -0,0 +1,132 @@
+package cagrr_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+var _ = Describe("Events", func() {
+	var publisher Publisher
+	BeforeEach(func() {
+		publisher = NewPublisher()
+	})
+
+	It("should filter events by cluster and keyspace", func() {
+		events, cancel := publisher.Subscribe([]string{"a"}, []string{"k"})
+		defer cancel()
+		publisher.Publish(&Event{Type: EventSkipped, Cluster: "b", Keyspace: "k"})
+		publisher.Publish(&Event{Type: EventSkipped, Cluster: "a", Keyspace: "x"})
+		publisher.Publish(&Event{Type: EventDispatched, Cluster: "a", Keyspace: "k", ID: 1})
+
+		var e *Event
+		Expect(events).To(Receive(&e))
+		Expect(e.Type).To(Equal(EventDispatched))
+		Expect(e.Time.IsZero()).To(BeFalse())
+		Expect(events).NotTo(Receive())
+	})
+
+	It("should stop delivery when subscription is cancelled", func() {
+		events, cancel := publisher.Subscribe(nil, nil)
+		cancel()
+		publisher.Publish(&Event{Type: EventComplete, Cluster: "a"})
+		Expect(events).NotTo(Receive())
+	})
+
+	It("should not block on slow subscribers", func() {
+		_, cancel := publisher.Subscribe(nil, nil)
+		defer cancel()
+		for i := 0; i < 1000; i++ {
+			publisher.Publish(&Event{Type: EventSkipped, Cluster: "a", ID: i})
+		}
+	})
+
+	It("should stop streaming when request is gone", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/api/events", nil).WithContext(ctx)
+		served := make(chan bool)
+		go func() {
+			defer close(served)
+			NewServer(nil, nil).PublishTo(publisher).ServeHTTP(httptest.NewRecorder(), req)
+		}()
+		Consistently(served).ShouldNot(BeClosed())
+		cancel()
+		Eventually(served).Should(BeClosed())
+	})
+
+	Context("Stream", func() {
+		var (
+			cajrr   *fakeCajrr
+			done    chan bool
+			stopped chan bool
+			web     *httptest.Server
+		)
+		BeforeEach(func() {
+			NewLogger("panic", "")
+			cajrr = newFakeCajrr(`[{"name": "t"}]`, 1)
+			tracker := NewTracker(memoryDB{}, NewRegulator(10))
+			cluster := cajrr.cluster("")
+			web = httptest.NewServer(NewServer(tracker, []*Cluster{cluster}).PublishTo(publisher))
+			done = make(chan bool)
+			stopped = make(chan bool)
+			go func(scheduler Scheduler) {
+				defer close(stopped)
+				scheduler.Schedule()
+			}(cluster.TrackIn(tracker).Until(done))
+		})
+		AfterEach(func() {
+			close(done)
+			Eventually(stopped).Should(BeClosed())
+			web.Close()
+			cajrr.Close()
+		})
+
+		It("should stream repair statuses as server-sent events", func() {
+			resp, err := http.Get(web.URL + "/api/events?cluster=c")
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			publisher.Publish(&Event{Type: EventDispatched, Cluster: "other"})
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+			body, _ := json.Marshal(&RepairStatus{Repair: *repair, Message: "done", Type: "COMPLETE"})
+			_, err = http.Post(web.URL+"/status", "application/json", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := bufio.NewReader(resp.Body)
+			readEvent := func() (string, *Event) {
+				kind, _ := reader.ReadString('\n')
+				data, _ := reader.ReadString('\n')
+				reader.ReadString('\n')
+				var e Event
+				Expect(json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &e)).To(Succeed())
+				return strings.TrimSpace(kind), &e
+			}
+
+			kind, e := readEvent()
+			Expect(kind).To(Equal("event: complete"))
+			Expect(e.Table).To(Equal("t"))
+			Expect(e.Message).To(Equal("done"))
+			Expect(e.Stats.TablePercent).To(BeNumerically("==", 100))
+
+			kind, e = readEvent()
+			Expect(kind).To(Equal("event: cluster_completed"))
+			Expect(e.Cluster).To(Equal("c"))
+		})
+
+		It("should not be served without publisher", func() {
+			recorder := httptest.NewRecorder()
+			NewServer(nil, nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package cagrr
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	healthOK     = "ok"
+	healthFailed = "failed"
+	probeTimeout = 5 * time.Second
+)
+
+var probeClient = &http.Client{Timeout: probeTimeout}
+
+// Ping checks that repair service of cluster answers
+func (c *Cluster) Ping() error {
+	resp, err := probeClient.Get(c.address() + "/keyspaces")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
+
+// ProbeWith checks given DB on readiness requests
+func (s *server) ProbeWith(db Pinger) Server {
+	s.db = db
+	return s
+}
+
+// handleHealth answers while process and callback listener are up
+func (s *server) handleHealth(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, &Health{
+		Status: healthOK,
+		Checks: []*HealthCheck{{Name: "listener", Status: healthOK}},
+	})
+}
+
+// handleReady checks DB and repair services of every cluster concurrently
+func (s *server) handleReady(w http.ResponseWriter, req *http.Request) {
+	checks := make(map[string]Pinger)
+	if s.db != nil {
+		checks["db"] = s.db
+	}
+	for _, c := range s.clusterSet() {
+		checks["cluster/"+c.Name] = c
+	}
+
+	health := &Health{Status: healthOK}
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for _, name := range sortedKeys(checks) {
+		check := &HealthCheck{Name: name, Status: healthOK}
+		health.Checks = append(health.Checks, check)
+
+		wg.Add(1)
+		go func(check *HealthCheck, pinger Pinger) {
+			defer wg.Done()
+			started := time.Now()
+			err := pinger.Ping()
+			check.Duration = time.Since(started)
+			if err != nil {
+				check.Status = healthFailed
+				check.Error = err.Error()
+				mutex.Lock()
+				health.Status = healthFailed
+				mutex.Unlock()
+			}
+		}(check, checks[name])
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if health.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
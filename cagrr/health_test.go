@@ -0,0 +1,74 @@
+package cagrr_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+type unreachableDB struct{}
+
+func (unreachableDB) Ping() error {
+	return errors.New("connection refused")
+}
+
+var _ = Describe("Health", func() {
+	var (
+		cajrr   *httptest.Server
+		cluster *Cluster
+	)
+	BeforeEach(func() {
+		cajrr = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`["k"]`))
+		}))
+		host, port, _ := net.SplitHostPort(cajrr.Listener.Addr().String())
+		cluster = &Cluster{Name: "c", Host: host}
+		cluster.Port, _ = strconv.Atoi(port)
+	})
+	AfterEach(func() {
+		cajrr.Close()
+	})
+
+	probe := func(server Server, path string) (int, *Health) {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var health Health
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &health)).To(Succeed())
+		return recorder.Code, &health
+	}
+	tracker := func() Tracker {
+		return NewTracker(memoryDB{}, NewRegulator(10))
+	}
+
+	It("should report live listener", func() {
+		code, health := probe(NewServer(tracker(), nil), "/healthz")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(health.Status).To(Equal("ok"))
+	})
+
+	It("should be ready when DB and repair services answer", func() {
+		code, health := probe(NewServer(tracker(), []*Cluster{cluster}).ProbeWith(memoryDB{}), "/readyz")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(health.Checks).To(HaveLen(2))
+		Expect(health.Checks[0].Name).To(Equal("cluster/c"))
+		Expect(health.Checks[1].Name).To(Equal("db"))
+	})
+
+	It("should detail failed dependencies", func() {
+		cajrr.Close()
+		code, health := probe(NewServer(tracker(), []*Cluster{cluster}).ProbeWith(unreachableDB{}), "/readyz")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(health.Status).To(Equal("failed"))
+		for _, check := range health.Checks {
+			Expect(check.Status).To(Equal("failed"))
+			Expect(check.Error).NotTo(BeEmpty())
+		}
+	})
+})
@@ -1,17 +1,30 @@
 package cagrr
 
-import "time"
+import (
+	"io"
+	"net/http"
+	"time"
+)
 
 // Closer closes DB connection
 type Closer interface {
 	Close()
 }
 
+// Cron is a parsed cron expression
+type Cron interface {
+	Contains(time.Time) bool
+	Next(time.Time) time.Time
+}
+
 // DB implements DB interface
 type DB interface {
 	CreateKey(keys ...string) string
 	ValueReader
 	ValueWriter
+	ValueDeleter
+	ValueLister
+	Pinger
 	Closer
 }
 
@@ -34,6 +47,23 @@ type Logger interface {
 	Info(message interface{}) Logger
 }
 
+// Metrics counts repair events of tracker and exposes them with repair progress in Prometheus text format
+type Metrics interface {
+	Tracker
+	Expose(w io.Writer, clusters []string) error
+}
+
+// Pinger checks availability of dependency
+type Pinger interface {
+	Ping() error
+}
+
+// Publisher delivers repair lifecycle events to subscribers
+type Publisher interface {
+	Publish(*Event)
+	Subscribe(clusters, keyspaces []string) (<-chan *Event, func())
+}
+
 // Regulator moderates the process
 type Regulator interface {
 	LimitRateTo(key string, duration time.Duration) time.Duration
@@ -41,30 +71,85 @@ type Regulator interface {
 	Rate(key string) time.Duration
 }
 
+// Retrier decides when failed repair should be retried
+type Retrier interface {
+	Backoff(attempt int) time.Duration
+	IsPoisoned(attempt int) bool
+}
+
+// Runner runs schedulers of configured clusters
+type Runner interface {
+	Clusters() []*Cluster
+	Run(*Config)
+	Stop(timeout time.Duration) bool
+}
+
 // Scheduler creates jobs in time
 type Scheduler interface {
+	CallbackAt(url string) Scheduler
+	Cancel(keyspace, table string) error
+	IsPaused() bool
+	Obtain(*RepairStatus) bool
+	Pause()
+	PublishTo(Publisher) Scheduler
+	Reconfigure(*Cluster)
 	RegulateWith(Regulator) Scheduler
+	Resume()
 	Schedule()
 	TrackIn(Tracker) Scheduler
+	Trigger(keyspace, table string, force bool) error
 	Until(chan bool) Scheduler
 }
 
 // Server serves repair handlers
 type Server interface {
+	AuditTo(io.Writer) Server
+	AuthorizeBy(token string) Server
+	MeasureBy(Metrics) Server
+	ProbeWith(db Pinger) Server
+	PublishTo(Publisher) Server
 	ServeAt(callback string) Server
+	ServeHTTP(http.ResponseWriter, *http.Request)
+	SetClusters([]*Cluster)
+}
+
+// Sink sends repair statistics to external metrics storage
+type Sink interface {
+	Closer
+	Send(*RepairStats) error
+	SendCluster(*ClusterStats) error
 }
 
 // Tracker keeps progress of repair
 type Tracker interface {
+	Children(keys ...string) map[string]*Track
 	Complete(cluster, keyspace, table string, repair int, err bool) *RepairStats
 	HasErrors(keys ...string) bool
 	IsCompleted(cluster, keyspace, table string, repair int, threshold time.Duration) bool
+	IsPaused(cluster string) bool
+	Poison(cluster, keyspace, table string, repair int) *RepairStats
+	Read(keys ...string) *Track
+	Reset(keys ...string) error
+	Restart(total int, keys ...string)
+	SetPaused(cluster string, paused bool)
 	Skip(cluster, keyspace, table string, repair int)
 	Start(cluster, keyspace, table string, repair int)
 	StartTable(cluster, keyspace, table string, total int)
 	StartKeyspace(cluster, keyspace string, total int)
 	StartCluster(cluster string, total int)
-	TrackError(cluster, keyspace, table string, id int)
+	TrackError(cluster, keyspace, table string, id int) *RepairStats
+	TrackTimeout(cluster, keyspace, table string, id int) *RepairStats
+}
+
+// ValueDeleter deletes position data from DB
+type ValueDeleter interface {
+	Delete(string, string)
+	DeleteTree(string, string) error
+}
+
+// ValueLister lists keys of DB by prefix
+type ValueLister interface {
+	ListKeys(string, string) ([]string, error)
 }
 
 // ValueReader reads position data from DB
@@ -76,3 +161,8 @@ type ValueReader interface {
 type ValueWriter interface {
 	WriteValue(string, string, []byte) error
 }
+
+// Window is a time range when repairs are allowed
+type Window interface {
+	Contains(time.Time) bool
+}
@@ -1,5 +1,34 @@
 package cagrr
 
+import (
+	"fmt"
+	"time"
+)
+
+// Configure table with settings given for it in keyspace
+func (k *Keyspace) Configure(table *Table) {
+	for _, settings := range k.TableSettings {
+		if settings.Name == table.Name {
+			table.Merge(settings)
+		}
+	}
+}
+
+// Filter tables by include and exclude patterns
+func (k *Keyspace) Filter(tables []*Table) []*Table {
+	var result []*Table
+	for _, t := range tables {
+		if len(k.include) > 0 && !Matches(k.include, t.Name) {
+			continue
+		}
+		if Matches(k.exclude, t.Name) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
 // SetTables to keyspace
 func (k *Keyspace) SetTables(tables []*Table) {
 	k.tables = tables
@@ -19,3 +48,65 @@ func (k *Keyspace) Tables() []*Table {
 func (k *Keyspace) Total() int {
 	return k.total
 }
+
+// UnmarshalYAML reads either list of keyspaces or a single name like "*"
+func (ks *Keyspaces) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*ks = Keyspaces{&Keyspace{Name: name}}
+		return nil
+	}
+	var list []*Keyspace
+	err := unmarshal(&list)
+	*ks = list
+	return err
+}
+
+// slicesOf table set explicitly for table or keyspace, otherwise the ones table already has
+func (k *Keyspace) slicesOf(table *Table) int {
+	slices := table.Slices
+	if k.Slices > 0 {
+		slices = k.Slices
+	}
+	for _, settings := range k.TableSettings {
+		if settings.Name == table.Name && settings.Slices > 0 {
+			slices = settings.Slices
+		}
+	}
+	return slices
+}
+
+func (k *Keyspace) prepare() error {
+	if err := checkDurations(k.Interval, k.Timeout); err != nil {
+		return fmt.Errorf("keyspace %s: %s", k.Name, err)
+	}
+	for _, t := range k.TableSettings {
+		if err := checkDurations(t.Interval, t.Timeout); err != nil {
+			return fmt.Errorf("keyspace %s: table %s: %s", k.Name, t.Name, err)
+		}
+	}
+	var err error
+	if k.include, err = ParsePatterns(k.IncludeTables); err != nil {
+		return fmt.Errorf("keyspace %s: include_tables: %s", k.Name, err)
+	}
+	if k.exclude, err = ParsePatterns(k.ExcludeTables); err != nil {
+		return fmt.Errorf("keyspace %s: exclude_tables: %s", k.Name, err)
+	}
+	return nil
+}
+
+func checkDurations(values ...string) error {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if duration <= 0 {
+			return fmt.Errorf("duration should be positive: %s", value)
+		}
+	}
+	return nil
+}
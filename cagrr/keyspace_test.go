@@ -0,0 +1,44 @@
+package cagrr_test
+
+import (
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Keyspace", func() {
+	var tables []*Table
+	BeforeEach(func() {
+		tables = []*Table{{Name: "users"}, {Name: "users_by_email"}, {Name: "events"}}
+	})
+
+	names := func(tables []*Table) []string {
+		var result []string
+		for _, t := range tables {
+			result = append(result, t.Name)
+		}
+		return result
+	}
+
+	configure := func(settings string) *Keyspace {
+		config, err := readConfig(testCluster + "    keyspaces:\n      - name: test\n" + settings)
+		Expect(err).To(BeNil())
+		return config.Clusters[0].Keyspaces[0]
+	}
+
+	It("should keep all tables without patterns", func() {
+		keyspace := &Keyspace{Name: "keyspace"}
+		Expect(names(keyspace.Filter(tables))).To(Equal([]string{"users", "users_by_email", "events"}))
+	})
+
+	It("should keep included tables only", func() {
+		keyspace := configure("        include_tables: [\"users*\"]\n")
+		Expect(names(keyspace.Filter(tables))).To(Equal([]string{"users", "users_by_email"}))
+	})
+
+	It("should skip excluded tables", func() {
+		keyspace := configure("        include_tables: [\"users*\"]\n        exclude_tables: [\"/_by_/\"]\n")
+		Expect(names(keyspace.Filter(tables))).To(Equal([]string{"users"}))
+	})
+})
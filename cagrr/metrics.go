@@ -0,0 +1,213 @@
+package cagrr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	metricsPrefix = "cagrr_"
+	dispatched    = "dispatched"
+	completed     = "completed"
+	failed        = "failed"
+	timedOut      = "timed_out"
+)
+
+// labelEscaper escapes label values as text exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// durationBuckets are upper bounds of fragment duration histogram in seconds
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200}
+
+// NewMetrics wraps tracker to count dispatched, completed, failed and timed out repairs
+func NewMetrics(tracker Tracker, regulator Regulator) Metrics {
+	return &metrics{
+		Tracker:    tracker,
+		buckets:    durationBuckets,
+		counters:   make(map[string]map[string]float64),
+		histograms: make(map[string]*histogram),
+		regulator:  regulator,
+	}
+}
+
+// Complete counts completed repair and observes its duration
+func (m *metrics) Complete(cluster, keyspace, table string, id int, err bool) *RepairStats {
+	stats := m.Tracker.Complete(cluster, keyspace, table, id, err)
+	if err {
+		m.count(failed, cluster, keyspace, table)
+		return stats
+	}
+	m.count(completed, cluster, keyspace, table)
+	m.observe(stats.Duration, cluster, keyspace)
+	return stats
+}
+
+// Expose writes progress of given clusters together with counted events
+func (m *metrics) Expose(w io.Writer, clusters []string) error {
+	out := bufio.NewWriter(w)
+	m.exposeProgress(out, clusters)
+
+	writeFamily(out, "regulator_rate_seconds", "gauge", "Average repair duration used by regulator to pace repairs")
+	for _, cluster := range clusters {
+		writeSample(out, "regulator_rate_seconds", labels("cluster", cluster), m.regulator.Rate(cluster).Seconds())
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.exposeCounters(out)
+	m.exposeHistograms(out)
+	return out.Flush()
+}
+
+// Start counts dispatched repair
+func (m *metrics) Start(cluster, keyspace, table string, id int) {
+	m.Tracker.Start(cluster, keyspace, table, id)
+	m.count(dispatched, cluster, keyspace, table)
+}
+
+// TrackError counts failed repair
+func (m *metrics) TrackError(cluster, keyspace, table string, id int) *RepairStats {
+	stats := m.Tracker.TrackError(cluster, keyspace, table, id)
+	m.count(failed, cluster, keyspace, table)
+	return stats
+}
+
+// TrackTimeout counts repair lost by timeout
+func (m *metrics) TrackTimeout(cluster, keyspace, table string, id int) *RepairStats {
+	m.count(timedOut, cluster, keyspace, table)
+	return m.Tracker.TrackTimeout(cluster, keyspace, table, id)
+}
+
+func (m *metrics) count(event, cluster, keyspace, table string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	values, ok := m.counters[event]
+	if !ok {
+		values = make(map[string]float64)
+		m.counters[event] = values
+	}
+	values[labels("cluster", cluster, "keyspace", keyspace, "table", table)]++
+}
+
+func (m *metrics) exposeCounters(out io.Writer) {
+	for _, event := range []string{dispatched, completed, failed, timedOut} {
+		name := "repairs_" + event + "_total"
+		writeFamily(out, name, "counter", fmt.Sprintf("Number of %s fragment repairs", strings.Replace(event, "_", " ", -1)))
+		values := m.counters[event]
+		for _, key := range sortedKeys(values) {
+			writeSample(out, name, key, values[key])
+		}
+	}
+}
+
+func (m *metrics) exposeHistograms(out io.Writer) {
+	name := "repair_duration_seconds"
+	writeFamily(out, name, "histogram", "Duration of completed fragment repairs")
+	for _, key := range sortedKeys(m.histograms) {
+		h := m.histograms[key]
+		for i, bound := range m.buckets {
+			writeSample(out, name+"_bucket", key+`,le="`+formatValue(bound)+`"`, float64(h.counts[i]))
+		}
+		writeSample(out, name+"_bucket", key+`,le="+Inf"`, float64(h.count))
+		writeSample(out, name+"_sum", key, h.sum)
+		writeSample(out, name+"_count", key, float64(h.count))
+	}
+}
+
+// exposeProgress writes tracks of clusters, their keyspaces and tables listed from database by every scrape,
+// so changes made by other processes and new keyspaces or tables are seen
+func (m *metrics) exposeProgress(out io.Writer, clusters []string) {
+	levels := []string{"cluster", "keyspace", "table"}
+	tracks := make([]map[string]*Track, len(levels))
+	for i := range tracks {
+		tracks[i] = make(map[string]*Track)
+	}
+	for _, cluster := range clusters {
+		tracks[0][labels("cluster", cluster)] = m.Read(cluster)
+		for keyspace, track := range m.Children(cluster) {
+			tracks[1][labels("cluster", cluster, "keyspace", keyspace)] = track
+			for table, track := range m.Children(cluster, keyspace) {
+				tracks[2][labels("cluster", cluster, "keyspace", keyspace, "table", table)] = track
+			}
+		}
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(*Track) float64
+	}{
+		{"percent", "Percent of repaired fragments of the current pass", func(t *Track) float64 { return float64(t.Percent) }},
+		{"errors", "Number of repair errors of the current pass", func(t *Track) float64 { return float64(t.Errors) }},
+		{"estimate_seconds", "Estimated time to complete the current pass", func(t *Track) float64 { return t.Estimate.Seconds() }},
+		{"last_success_timestamp_seconds", "Time of the last completed pass", func(t *Track) float64 {
+			if t.Finished.IsZero() {
+				return 0
+			}
+			return float64(t.Finished.UnixNano()) / float64(time.Second)
+		}},
+	}
+	for i, level := range levels {
+		for _, gauge := range gauges {
+			name := level + "_" + gauge.name
+			writeFamily(out, name, "gauge", fmt.Sprintf("%s of %s", gauge.help, level))
+			for _, key := range sortedKeys(tracks[i]) {
+				writeSample(out, name, key, gauge.value(tracks[i][key]))
+			}
+		}
+	}
+}
+
+func (m *metrics) observe(duration time.Duration, cluster, keyspace string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := labels("cluster", cluster, "keyspace", keyspace)
+	h, ok := m.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.histograms[key] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labels renders name and value pairs as Prometheus label set without braces
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func writeFamily(out io.Writer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n", metricsPrefix, name, help)
+	fmt.Fprintf(out, "# TYPE %s%s %s\n", metricsPrefix, name, kind)
+}
+
+func writeSample(out io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(out, "%s%s{%s} %s\n", metricsPrefix, name, labels, formatValue(value))
+}
@@ -0,0 +1,131 @@
+package cagrr_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+// listingDB counts listings of memory database
+type listingDB struct {
+	memoryDB
+	listings int
+}
+
+func (l *listingDB) ListKeys(table, prefix string) ([]string, error) {
+	l.listings++
+	return l.memoryDB.ListKeys(table, prefix)
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		db        *listingDB
+		metrics   Metrics
+		regulator Regulator
+	)
+	BeforeEach(func() {
+		regulator = NewRegulator(10)
+		db = &listingDB{memoryDB: memoryDB{}}
+		metrics = NewMetrics(NewTracker(db, regulator), regulator)
+		metrics.StartCluster("c", 4)
+		metrics.StartKeyspace("c", "k", 4)
+		metrics.StartTable("c", "k", "t", 4)
+		for id := 1; id <= 4; id++ {
+			metrics.Start("c", "k", "t", id)
+		}
+		metrics.Complete("c", "k", "t", 1, false)
+		metrics.Complete("c", "k", "t", 2, false)
+		metrics.TrackError("c", "k", "t", 3)
+		metrics.TrackTimeout("c", "k", "t", 4)
+	})
+
+	expose := func() []string {
+		var buf bytes.Buffer
+		Expect(metrics.Expose(&buf, []string{"c"})).To(Succeed())
+		return strings.Split(buf.String(), "\n")
+	}
+
+	It("should expose progress gauges of every level", func() {
+		lines := expose()
+		Expect(lines).To(ContainElement(`cagrr_cluster_percent{cluster="c"} 50`))
+		Expect(lines).To(ContainElement(`cagrr_keyspace_errors{cluster="c",keyspace="k"} 2`))
+		Expect(lines).To(ContainElement(`cagrr_table_percent{cluster="c",keyspace="k",table="t"} 50`))
+		Expect(lines).To(ContainElement(`cagrr_table_last_success_timestamp_seconds{cluster="c",keyspace="k",table="t"} 0`))
+		Expect(lines).To(ContainElement("# TYPE cagrr_cluster_estimate_seconds gauge"))
+	})
+
+	It("should list progress again by every scrape", func() {
+		expose()
+		listings := db.listings
+		metrics.StartTable("c", "k", "u", 1)
+		metrics.Complete("c", "k", "t", 3, false)
+
+		lines := expose()
+		Expect(lines).To(ContainElement(`cagrr_cluster_percent{cluster="c"} 75`))
+		Expect(lines).To(ContainElement(`cagrr_table_percent{cluster="c",keyspace="k",table="t"} 75`))
+		Expect(lines).To(ContainElement(`cagrr_table_percent{cluster="c",keyspace="k",table="u"} 0`))
+		Expect(db.listings).To(BeNumerically(">", listings))
+	})
+
+	It("should see progress changed by other tracker", func() {
+		expose()
+		other := NewTracker(db, regulator)
+		other.StartKeyspace("c", "l", 1)
+		other.StartTable("c", "l", "v", 1)
+		lines := expose()
+		Expect(lines).To(ContainElement(`cagrr_table_percent{cluster="c",keyspace="l",table="v"} 0`))
+
+		Expect(other.Reset("c", "k", "t")).To(Succeed())
+		lines = expose()
+		Expect(lines).NotTo(ContainElement(HavePrefix(`cagrr_table_percent{cluster="c",keyspace="k",table="t"}`)))
+	})
+
+	It("should reset with no keys", func() {
+		Expect(func() { metrics.Reset() }).NotTo(Panic())
+	})
+
+	It("should count repair events", func() {
+		lines := expose()
+		labels := `{cluster="c",keyspace="k",table="t"}`
+		Expect(lines).To(ContainElement("cagrr_repairs_dispatched_total" + labels + " 4"))
+		Expect(lines).To(ContainElement("cagrr_repairs_completed_total" + labels + " 2"))
+		Expect(lines).To(ContainElement("cagrr_repairs_failed_total" + labels + " 1"))
+		Expect(lines).To(ContainElement("cagrr_repairs_timed_out_total" + labels + " 1"))
+	})
+
+	It("should observe durations of completed repairs", func() {
+		lines := expose()
+		Expect(lines).To(ContainElement("# TYPE cagrr_repair_duration_seconds histogram"))
+		Expect(lines).To(ContainElement(`cagrr_repair_duration_seconds_bucket{cluster="c",keyspace="k",le="1"} 2`))
+		Expect(lines).To(ContainElement(`cagrr_repair_duration_seconds_bucket{cluster="c",keyspace="k",le="+Inf"} 2`))
+		Expect(lines).To(ContainElement(`cagrr_repair_duration_seconds_count{cluster="c",keyspace="k"} 2`))
+	})
+
+	It("should expose regulator rate", func() {
+		rate := strconv.FormatFloat(regulator.Rate("c").Seconds(), 'g', -1, 64)
+		Expect(expose()).To(ContainElement(`cagrr_regulator_rate_seconds{cluster="c"} ` + rate))
+	})
+
+	It("should escape label values", func() {
+		metrics.Start("c", "k", `a"b\c`, 1)
+		Expect(expose()).To(ContainElement(`cagrr_repairs_dispatched_total{cluster="c",keyspace="k",table="a\"b\\c"} 1`))
+	})
+
+	It("should be served by server only when enabled", func() {
+		recorder := httptest.NewRecorder()
+		NewServer(metrics, []*Cluster{{Name: "c"}}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+		recorder = httptest.NewRecorder()
+		NewServer(metrics, []*Cluster{{Name: "c"}}).MeasureBy(metrics).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(recorder.Body.String()).To(ContainSubstring(`cagrr_cluster_percent{cluster="c"} 50`))
+	})
+})
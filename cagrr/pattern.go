@@ -0,0 +1,41 @@
+package cagrr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ParsePattern compiles glob pattern or regular expression surrounded by slashes
+func ParsePattern(spec string) (*regexp.Regexp, error) {
+	if len(spec) > 1 && strings.HasPrefix(spec, "/") && strings.HasSuffix(spec, "/") {
+		return regexp.Compile(spec[1 : len(spec)-1])
+	}
+	expr := regexp.QuoteMeta(spec)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// ParsePatterns compiles list of patterns
+func ParsePatterns(specs []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, spec := range specs {
+		pattern, err := ParsePattern(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %s", spec, err)
+		}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+// Matches checks that name matches any of patterns
+func Matches(patterns []*regexp.Regexp, name string) bool {
+	for _, p := range patterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package cagrr_test
+
+import (
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pattern", func() {
+	It("should match glob", func() {
+		patterns, err := ParsePatterns([]string{"system*"})
+		Expect(err).To(BeNil())
+		Expect(Matches(patterns, "system_auth")).To(BeTrue())
+		Expect(Matches(patterns, "my_system")).To(BeFalse())
+	})
+
+	It("should match whole name by glob", func() {
+		patterns, _ := ParsePatterns([]string{"user?"})
+		Expect(Matches(patterns, "users")).To(BeTrue())
+		Expect(Matches(patterns, "users_by_email")).To(BeFalse())
+	})
+
+	It("should match regular expression", func() {
+		patterns, err := ParsePatterns([]string{"/_by_/"})
+		Expect(err).To(BeNil())
+		Expect(Matches(patterns, "users_by_email")).To(BeTrue())
+	})
+
+	It("should fail on invalid regular expression", func() {
+		_, err := ParsePatterns([]string{"/(/"})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("shouldn't match without patterns", func() {
+		Expect(Matches(nil, "users")).To(BeFalse())
+	})
+})
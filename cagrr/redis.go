@@ -33,6 +33,24 @@ func (r *redisDB) Delete(table, key string) {
 	r.db.Del(key)
 }
 
+func (r *redisDB) DeleteTree(table, prefix string) error {
+	keys, err := r.db.Keys(prefix + "*").Result()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	return r.db.Del(keys...).Err()
+}
+
+func (r *redisDB) ListKeys(table, prefix string) ([]string, error) {
+	return r.db.Keys(prefix + "*").Result()
+}
+
+// Ping checks that redis answers
+func (r *redisDB) Ping() error {
+	_, err := r.db.Ping().Result()
+	return err
+}
+
 func (r *redisDB) ReadValue(table, key string) []byte {
 	result, _ := r.db.Get(key).Bytes()
 	return result
@@ -18,6 +18,8 @@ func (r *regulator) Limit(key string) {
 }
 
 func (r *regulator) LimitRateTo(key string, duration time.Duration) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	queue := r.getQueue(key)
 	queue.Push(duration)
 	result := queue.Average()
@@ -25,6 +27,8 @@ func (r *regulator) LimitRateTo(key string, duration time.Duration) time.Duratio
 }
 
 func (r *regulator) Rate(key string) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	queue := r.getQueue(key)
 	rate := queue.Average()
 	return rate
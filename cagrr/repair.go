@@ -0,0 +1,117 @@
+package cagrr
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseTokenRange parses "start:end" token range
+func ParseTokenRange(spec string) (*big.Int, *big.Int, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("Token range should look like start:end, got %q", spec)
+	}
+	start, ok := new(big.Int).SetString(parts[0], 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("Wrong start token %q", parts[0])
+	}
+	end, ok := new(big.Int).SetString(parts[1], 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("Wrong end token %q", parts[1])
+	}
+	return start, end, nil
+}
+
+// Endpoints of repair: its own endpoint and all known replicas
+func (r *Repair) Endpoints() []string {
+	result := []string{r.Endpoint}
+	for _, replica := range r.Replicas {
+		if !r.touches(result, replica) {
+			result = append(result, replica)
+		}
+	}
+	return result
+}
+
+// Key identifies repair inside of cluster
+func (r *Repair) Key() string {
+	return fmt.Sprintf("%s/%s/%d", r.Keyspace, r.Table, r.ID)
+}
+
+// Overlaps checks that token range of repair intersects with given one, both may wrap around the ring
+func (r *Repair) Overlaps(start, end *big.Int) bool {
+	s, ok := new(big.Int).SetString(r.Start, 10)
+	if !ok {
+		return false
+	}
+	e, ok := new(big.Int).SetString(r.End, 10)
+	if !ok {
+		return false
+	}
+	for _, a := range tokenRanges(s, e) {
+		for _, b := range tokenRanges(start, end) {
+			if a.overlaps(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Touches checks that endpoint takes part in repair
+func (r *Repair) Touches(endpoint string) bool {
+	return r.touches(r.Endpoints(), endpoint)
+}
+
+func (r *Repair) touches(endpoints []string, endpoint string) bool {
+	for _, e := range endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func (s byPriority) Len() int {
+	return len(s)
+}
+
+// Less puts repairs of tables at risk of missing their deadline first, the earliest deadline first among them.
+// Repairs of heavier tables go first among the rest, then the earliest deadline and unlimited ones last
+func (s byPriority) Less(i, j int) bool {
+	a, b := s[i], s[j]
+	if a.atRisk != b.atRisk {
+		return a.atRisk
+	}
+	if !a.atRisk && a.weight != b.weight {
+		return a.weight > b.weight
+	}
+	if !a.deadline.Equal(b.deadline) {
+		if a.deadline.IsZero() || b.deadline.IsZero() {
+			return !a.deadline.IsZero()
+		}
+		return a.deadline.Before(b.deadline)
+	}
+	return a.weight > b.weight
+}
+
+func (s byPriority) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// tokenRanges splits range wrapping around the ring, nil bound stands for the end of ring
+func tokenRanges(start, end *big.Int) []tokenRange {
+	if start.Cmp(end) < 0 {
+		return []tokenRange{{start, end}}
+	}
+	return []tokenRange{{start, nil}, {nil, end}}
+}
+
+func (a tokenRange) overlaps(b tokenRange) bool {
+	return tokenLess(a.start, b.end) && tokenLess(b.start, a.end)
+}
+
+func tokenLess(start, end *big.Int) bool {
+	return start == nil || end == nil || start.Cmp(end) < 0
+}
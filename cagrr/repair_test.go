@@ -0,0 +1,81 @@
+package cagrr_test
+
+import (
+	"math/big"
+
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Repair", func() {
+	var repair *Repair
+	BeforeEach(func() {
+		repair = &Repair{
+			ID:       5,
+			Cluster:  "cluster",
+			Keyspace: "keyspace",
+			Table:    "table",
+			Endpoint: "10.0.0.1",
+			Replicas: []string{"10.0.0.1", "10.0.0.2"},
+		}
+	})
+
+	It("should be identified by keyspace, table and fragment", func() {
+		Expect(repair.Key()).To(Equal("keyspace/table/5"))
+	})
+
+	Context("endpoints", func() {
+		It("should include endpoint and replicas once", func() {
+			Expect(repair.Endpoints()).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		})
+		It("should touch replica", func() {
+			Expect(repair.Touches("10.0.0.2")).To(BeTrue())
+		})
+		It("shouldn't touch foreign node", func() {
+			Expect(repair.Touches("10.0.0.3")).To(BeFalse())
+		})
+	})
+
+	Context("token range", func() {
+		overlaps := func(spec string) bool {
+			start, end, err := ParseTokenRange(spec)
+			Expect(err).NotTo(HaveOccurred())
+			return repair.Overlaps(start, end)
+		}
+
+		BeforeEach(func() {
+			repair.Start = "-100"
+			repair.End = "100"
+		})
+
+		It("should parse negative tokens", func() {
+			start, end, err := ParseTokenRange("-9223372036854775808:-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(start.Cmp(big.NewInt(-9223372036854775808))).To(Equal(0))
+			Expect(end.Cmp(big.NewInt(-1))).To(Equal(0))
+		})
+		It("should reject malformed range", func() {
+			_, _, err := ParseTokenRange("1-2")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should overlap intersecting range", func() {
+			Expect(overlaps("50:150")).To(BeTrue())
+			Expect(overlaps("-200:-50")).To(BeTrue())
+		})
+		It("shouldn't overlap adjacent range", func() {
+			Expect(overlaps("100:200")).To(BeFalse())
+		})
+		It("should overlap range wrapping around the ring", func() {
+			Expect(overlaps("200:-50")).To(BeTrue())
+			Expect(overlaps("200:-150")).To(BeFalse())
+		})
+		It("should handle repair wrapping around the ring", func() {
+			repair.Start = "100"
+			repair.End = "-100"
+			Expect(overlaps("150:200")).To(BeTrue())
+			Expect(overlaps("-50:50")).To(BeFalse())
+		})
+	})
+})
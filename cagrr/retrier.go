@@ -0,0 +1,40 @@
+package cagrr
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	maxBackoff = time.Hour
+)
+
+// NewRetrier creates retry policy with exponential backoff
+func NewRetrier(attempts int, backoff time.Duration) Retrier {
+	return &retrier{
+		attempts: attempts,
+		backoff:  backoff,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Backoff returns delay before next attempt: exponential growth with jitter
+func (r *retrier) Backoff(attempt int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay = delay * 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	half := int64(delay) / 2
+	if half == 0 {
+		return delay
+	}
+	return time.Duration(half + r.random.Int63n(half))
+}
+
+// IsPoisoned checks that no attempts left
+func (r *retrier) IsPoisoned(attempt int) bool {
+	return attempt >= r.attempts
+}
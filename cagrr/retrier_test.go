@@ -0,0 +1,42 @@
+package cagrr_test
+
+import (
+	"time"
+
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retrier", func() {
+	var retrier Retrier
+	BeforeEach(func() {
+		retrier = NewRetrier(3, time.Second*10)
+	})
+
+	Context("backoff", func() {
+		It("should keep first delay within jitter", func() {
+			delay := retrier.Backoff(1)
+			Expect(delay).To(BeNumerically(">=", time.Second*5))
+			Expect(delay).To(BeNumerically("<", time.Second*10))
+		})
+		It("should grow exponentially", func() {
+			delay := retrier.Backoff(3)
+			Expect(delay).To(BeNumerically(">=", time.Second*20))
+			Expect(delay).To(BeNumerically("<", time.Second*40))
+		})
+		It("should be limited", func() {
+			Expect(retrier.Backoff(100)).To(BeNumerically("<", time.Hour))
+		})
+	})
+
+	Context("poisoning", func() {
+		It("should allow attempts under limit", func() {
+			Expect(retrier.IsPoisoned(2)).To(BeFalse())
+		})
+		It("should poison after last attempt", func() {
+			Expect(retrier.IsPoisoned(3)).To(BeTrue())
+		})
+	})
+})
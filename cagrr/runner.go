@@ -0,0 +1,154 @@
+package cagrr
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NewRunner creates runner of cluster schedulers, their repair events are published to given publisher
+func NewRunner(tracker Tracker, regulator Regulator, server Server, events Publisher) Runner {
+	return &runner{
+		configs:   make(map[string][]byte),
+		dones:     make(map[string]chan bool),
+		events:    events,
+		regulator: regulator,
+		running:   make(map[string]*Cluster),
+		server:    server,
+		stopping:  make(map[*Cluster]bool),
+		tracker:   tracker,
+	}
+}
+
+// Clusters returns running clusters sorted by name
+func (r *runner) Clusters() []*Cluster {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.clusters()
+}
+
+// Run schedulers of configuration: starts new clusters, stops removed ones
+// and reconfigures changed ones leaving untouched clusters as is
+func (r *runner) Run(config *Config) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	configured := make(map[string]*Cluster)
+	for _, c := range config.Clusters {
+		configured[c.Name] = c
+	}
+
+	for name := range r.running {
+		if _, ok := configured[name]; !ok {
+			r.stop(name)
+		}
+	}
+	for name, c := range configured {
+		settings := settingsOf(c)
+		previous, ok := r.configs[name]
+		switch {
+		case !ok:
+			r.start(c, settings)
+		case settings == nil || !bytes.Equal(previous, settings):
+			r.running[name].Reconfigure(c)
+			r.configs[name] = settings
+		}
+	}
+	r.server.SetClusters(r.routable())
+}
+
+// Stop all clusters and wait for them up to given timeout
+func (r *runner) Stop(timeout time.Duration) bool {
+	r.mutex.Lock()
+	for name := range r.running {
+		r.stop(name)
+	}
+	r.server.SetClusters(r.routable())
+	r.mutex.Unlock()
+
+	stopped := make(chan bool)
+	go func() {
+		r.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (r *runner) clusters() []*Cluster {
+	names := make([]string, 0, len(r.running))
+	for name := range r.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]*Cluster, 0, len(names))
+	for _, name := range names {
+		result = append(result, r.running[name])
+	}
+	return result
+}
+
+// routable clusters receive repair statuses: running ones and stopping ones awaiting their repairs
+func (r *runner) routable() []*Cluster {
+	result := r.clusters()
+	for c := range r.stopping {
+		result = append(result, c)
+	}
+	return result
+}
+
+// start cluster with settings marshaled before its scheduler begins to change it
+func (r *runner) start(c *Cluster, settings []byte) {
+	done := make(chan bool)
+	r.configs[c.Name] = settings
+	r.dones[c.Name] = done
+	r.running[c.Name] = c
+
+	log.WithFields(c).Info("Cluster started")
+	r.wg.Add(1)
+	go func(scheduler Scheduler) {
+		defer r.wg.Done()
+		scheduler.Schedule()
+		r.stopped(c)
+	}(c.
+		PublishTo(r.events).
+		RegulateWith(r.regulator).
+		TrackIn(r.tracker).
+		Until(done))
+}
+
+// stop cluster, it stays routable until its scheduler returns
+func (r *runner) stop(name string) {
+	c := r.running[name]
+	close(r.dones[name])
+	c.logger().Info("Cluster stopping")
+	r.stopping[c] = true
+	delete(r.configs, name)
+	delete(r.dones, name)
+	delete(r.running, name)
+}
+
+// stopped forgets cluster when its scheduler returns
+func (r *runner) stopped(c *Cluster) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.stopping, c)
+	r.server.SetClusters(r.routable())
+}
+
+// settingsOf is configuration file representation of cluster, nil when it can't be marshaled
+func settingsOf(c *Cluster) []byte {
+	result, err := yaml.Marshal(c)
+	if err != nil {
+		return nil
+	}
+	return result
+}
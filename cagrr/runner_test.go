@@ -0,0 +1,116 @@
+package cagrr_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+var _ = Describe("Runner", func() {
+	var (
+		cajrr   *fakeCajrr
+		runner  Runner
+		server  Server
+		tracker Tracker
+	)
+	BeforeEach(func() {
+		NewLogger("panic", "")
+		cajrr = newFakeCajrr(`[{"name": "t"}]`, 1)
+		tracker = NewTracker(memoryDB{}, NewRegulator(10))
+		server = NewServer(tracker, nil)
+		runner = NewRunner(tracker, NewRegulator(10), server, NewPublisher())
+	})
+	AfterEach(func() {
+		cajrr.Close()
+	})
+
+	Context("run", func() {
+		AfterEach(func() {
+			Expect(runner.Stop(time.Second)).To(BeTrue())
+		})
+
+		It("should start added clusters", func() {
+			cluster := cajrr.cluster("")
+			runner.Run(&Config{Clusters: []*Cluster{cluster}})
+			Expect(runner.Clusters()).To(Equal([]*Cluster{cluster}))
+
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+			callback(server, repair, "COMPLETE")
+			Expect(tracker.Read("c", "k", "t").Count).To(Equal(1))
+		})
+
+		It("should keep removed cluster routable until its repairs complete", func() {
+			runner.Run(&Config{Clusters: []*Cluster{cajrr.cluster("")}})
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+
+			runner.Run(&Config{})
+			Expect(runner.Clusters()).To(BeEmpty())
+			callback(server, repair, "COMPLETE")
+			Expect(tracker.Read("c", "k", "t").Count).To(Equal(1))
+		})
+
+		It("should reconfigure changed clusters in place", func() {
+			cluster := cajrr.cluster("")
+			runner.Run(&Config{Clusters: []*Cluster{cluster}})
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+			callback(server, repair, "COMPLETE")
+
+			moved := newFakeCajrr(`[{"name": "t"}]`, 1)
+			defer moved.Close()
+			settings := moved.cluster("")
+			settings.TrackIn(tracker)
+			source := settings.Status("", "")[0].Source
+			runner.Run(&Config{Clusters: []*Cluster{settings}})
+			Expect(runner.Clusters()).To(Equal([]*Cluster{cluster}))
+			cajrr.Close()
+			Eventually(cluster.Ping).Should(Succeed())
+
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/clusters/c", nil))
+			var progress Progress
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &progress)).To(Succeed())
+			Expect(progress.Source).To(Equal(source))
+
+			Eventually(func() error { return cluster.Trigger("", "", true) }).Should(Succeed())
+			Eventually(moved.repairs, "3s").Should(Receive(&repair))
+			callback(server, repair, "COMPLETE")
+		})
+
+		It("should leave clusters with the same settings untouched", func() {
+			cluster := cajrr.cluster("")
+			keyspace := cluster.Keyspaces[0]
+			runner.Run(&Config{Clusters: []*Cluster{cluster}})
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+
+			runner.Run(&Config{Clusters: []*Cluster{cajrr.cluster("")}})
+			Expect(runner.Clusters()).To(Equal([]*Cluster{cluster}))
+			Consistently(func() *Keyspace { return cluster.Keyspaces[0] }, "200ms").Should(BeIdenticalTo(keyspace))
+			callback(server, repair, "COMPLETE")
+		})
+	})
+
+	Context("stop", func() {
+		It("should stop idle clusters", func() {
+			Expect(runner.Stop(time.Second)).To(BeTrue())
+		})
+
+		It("should give up waiting for running repairs after timeout", func() {
+			runner.Run(&Config{Clusters: []*Cluster{cajrr.cluster("")}})
+			var repair *Repair
+			Eventually(cajrr.repairs).Should(Receive(&repair))
+
+			Expect(runner.Stop(100 * time.Millisecond)).To(BeFalse())
+			callback(server, repair, "COMPLETE")
+			Expect(runner.Stop(time.Second)).To(BeTrue())
+		})
+	})
+})
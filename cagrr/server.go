@@ -10,17 +10,45 @@ import (
 )
 
 const (
-	week = time.Hour * 160
+	week = time.Hour * 24 * 7
 )
 
 // NewServer initializes loops for scheduling repair jobs
-func NewServer(tracker Tracker) Server {
+func NewServer(tracker Tracker, clusters []*Cluster) Server {
 	s := server{
-		tracker: tracker,
+		clusters: clusters,
+		last:     make(map[string]*RepairStats),
+		mux:      http.NewServeMux(),
+		tracker:  tracker,
 	}
+	s.mux.Handle("/status", http.HandlerFunc(s.handleRepairStatus))
+	s.mux.Handle("/healthz", http.HandlerFunc(s.handleHealth))
+	s.mux.Handle("/readyz", http.HandlerFunc(s.handleReady))
+	s.mux.Handle("/metrics", http.HandlerFunc(s.handleMetrics))
+	s.mux.Handle("/api/events", http.HandlerFunc(s.handleEvents))
+	s.mux.Handle("/api/clusters", http.HandlerFunc(s.handleClusters))
+	s.mux.Handle("/api/clusters/", http.HandlerFunc(s.handleClusters))
 	return &s
 }
 
+// MeasureBy exposes given metrics at /metrics
+func (s *server) MeasureBy(m Metrics) Server {
+	s.metrics = m
+	return s
+}
+
+// SetClusters to route repair statuses to
+func (s *server) SetClusters(clusters []*Cluster) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clusters = clusters
+}
+
+// ServeHTTP routes requests of callbacks and API
+func (s *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(w, req)
+}
+
 func (s *server) ServeAt(callback string) Server {
 	s.callback = callback
 	go s.startServer()
@@ -38,6 +66,47 @@ func (s *server) handleRepairStatus(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (s *server) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	if s.metrics == nil {
+		http.NotFound(w, req)
+		return
+	}
+	var names []string
+	for _, c := range s.clusterSet() {
+		names = append(names, c.Name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.metrics.Expose(w, names); err != nil {
+		log.WithError(err).Warn("Metrics write error")
+	}
+}
+
+// obtain passes status to cluster, clusters aren't locked while it waits for free space in queue.
+// Stopping cluster may share name with its replacement, status belongs to the one awaiting it.
+// False means status is stale or unknown and shouldn't be tracked
+func (s *server) obtain(status RepairStatus) bool {
+	s.mutex.RLock()
+	var clusters []*Cluster
+	for _, c := range s.clusters {
+		if c.Name == status.Repair.Cluster {
+			clusters = append(clusters, c)
+		}
+	}
+	s.mutex.RUnlock()
+
+	if len(clusters) == 0 {
+		log.WithFields(status.Repair).Warn("Status of unknown cluster received")
+		return false
+	}
+	for _, c := range clusters {
+		if c.Obtain(&status) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *server) processComplete(status RepairStatus) {
 	repair := &status.Repair
 	cluster := repair.Cluster
@@ -47,15 +116,12 @@ func (s *server) processComplete(status RepairStatus) {
 
 	stats := s.tracker.Complete(cluster, keyspace, table, id, false)
 	log.WithFields(stats).Info(status.Message)
+	s.remember(stats)
+	s.publish(EventComplete, repair, status.Message, stats)
 
 	if stats.ClusterPercent == 100 {
-		duration := int64(stats.ClusterAverage) * int64(stats.ClusterCompleted)
-		clusterStats := &ClusterStats{
-			Cluster:            stats.Cluster,
-			ClusterDuration:    time.Duration(duration),
-			LastClusterSuccess: time.Now(),
-		}
-		log.WithFields(clusterStats).Info("Cluster completed")
+		log.WithFields(clusterStatsOf(stats)).Info("Cluster completed")
+		s.publish(EventClusterCompleted, &Repair{Cluster: cluster}, "Cluster completed", nil)
 	}
 
 }
@@ -63,14 +129,12 @@ func (s *server) processComplete(status RepairStatus) {
 func (s *server) processFail(status RepairStatus) {
 	repair := status.Repair
 	s.tracker.TrackError(repair.Cluster, repair.Keyspace, repair.Table, repair.ID)
+	s.publish(EventError, &repair, status.Message, nil)
 }
 
 func (s *server) startServer() {
 	for {
 		log.Info(fmt.Sprintf("Server listen at %s", s.callback))
-
-		s.mux = http.NewServeMux()
-		s.mux.Handle("/status", http.HandlerFunc(s.handleRepairStatus))
 		log.Fatal(http.ListenAndServe(s.callback, s.mux))
 	}
 }
@@ -79,10 +143,14 @@ func (s *server) trackStatus(status RepairStatus) error {
 	var err error
 	switch status.Type {
 	case "COMPLETE":
-		s.processComplete(status)
+		if s.obtain(status) {
+			s.processComplete(status)
+		}
 	case "ERROR":
 		err = errors.New("Error in cajrr")
-		s.processFail(status)
+		if s.obtain(status) {
+			s.processFail(status)
+		}
 	}
 	return err
 }
@@ -0,0 +1,291 @@
+package cagrr
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	allLevels         = "_all"
+	defaultSinkPrefix = "cagrr.{cluster}.{keyspace}.{table}"
+	sendBuffer        = 1024
+	sinkTimeout       = time.Second
+)
+
+var (
+	placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+	unsafeName  = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// NewSink creates sink of configured type
+func NewSink(config *SinkConfig) (Sink, error) {
+	switch config.Type {
+	case "statsd":
+		return NewStatsdSink(config.Address, config.Prefix)
+	case "graphite":
+		return NewGraphiteSink(config.Address, config.Prefix), nil
+	}
+	return nil, fmt.Errorf("Unknown metrics sink type %q", config.Type)
+}
+
+// NewSender wraps tracker to send statistics of completed, failed and timed out repairs to sinks.
+// Statistics are sent in background and dropped while sinks lag behind
+func NewSender(tracker Tracker, sinks ...Sink) Tracker {
+	s := &sender{
+		Tracker: tracker,
+		queue:   make(chan interface{}, sendBuffer),
+		sinks:   sinks,
+	}
+	go s.deliver()
+	return s
+}
+
+// NewGraphiteSink creates sink writing plaintext protocol lines over TCP, connection is established on demand
+func NewGraphiteSink(address, prefix string) Sink {
+	if prefix == "" {
+		prefix = defaultSinkPrefix
+	}
+	return &graphiteSink{
+		address: address,
+		prefix:  prefix,
+	}
+}
+
+// NewStatsdSink creates sink sending gauges and timings over UDP
+func NewStatsdSink(address, prefix string) (Sink, error) {
+	if prefix == "" {
+		prefix = defaultSinkPrefix
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{
+		conn:   conn,
+		prefix: prefix,
+	}, nil
+}
+
+// Complete sends statistics of repair together with statistics of cluster which pass is over
+func (s *sender) Complete(cluster, keyspace, table string, id int, err bool) *RepairStats {
+	stats := s.Tracker.Complete(cluster, keyspace, table, id, err)
+	if err {
+		s.send(untimed(stats))
+		return stats
+	}
+	s.send(stats)
+	if stats.ClusterPercent == 100 {
+		s.send(clusterStatsOf(stats))
+	}
+	return stats
+}
+
+// TrackError sends statistics of failed repair
+func (s *sender) TrackError(cluster, keyspace, table string, id int) *RepairStats {
+	stats := s.Tracker.TrackError(cluster, keyspace, table, id)
+	s.send(untimed(stats))
+	return stats
+}
+
+// TrackTimeout sends statistics of repair lost by timeout
+func (s *sender) TrackTimeout(cluster, keyspace, table string, id int) *RepairStats {
+	stats := s.Tracker.TrackTimeout(cluster, keyspace, table, id)
+	s.send(untimed(stats))
+	return stats
+}
+
+// deliver queued statistics to every sink
+func (s *sender) deliver() {
+	for stats := range s.queue {
+		for _, sink := range s.sinks {
+			var err error
+			switch stats := stats.(type) {
+			case *RepairStats:
+				err = sink.Send(stats)
+			case *ClusterStats:
+				err = sink.SendCluster(stats)
+			}
+			if err != nil {
+				log.WithError(err).Warn("Metrics send error")
+			}
+		}
+	}
+}
+
+// send queues statistics without waiting for sinks
+func (s *sender) send(stats interface{}) {
+	select {
+	case s.queue <- stats:
+	default:
+		log.Warn("Metrics queue is full, statistics are dropped")
+	}
+}
+
+// Close connection to Graphite
+func (s *graphiteSink) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Send statistics of completed repair to Graphite
+func (s *graphiteSink) Send(stats *RepairStats) error {
+	return s.write(repairPoints(s.prefix, stats))
+}
+
+// SendCluster sends statistics of completed cluster to Graphite
+func (s *graphiteSink) SendCluster(stats *ClusterStats) error {
+	return s.write(clusterPoints(s.prefix, stats))
+}
+
+// write points reconnecting once when connection is broken
+func (s *graphiteSink) write(points []point) error {
+	var buf bytes.Buffer
+	now := time.Now().Unix()
+	for _, p := range points {
+		fmt.Fprintf(&buf, "%s %s %d\n", p.name, strconv.FormatFloat(p.value, 'f', -1, 64), now)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = net.DialTimeout("tcp", s.address, sinkTimeout); err != nil {
+				s.conn = nil
+				return err
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+		if _, err = s.conn.Write(buf.Bytes()); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// Close UDP socket
+func (s *statsdSink) Close() {
+	s.conn.Close()
+}
+
+// Send statistics of completed repair to StatsD
+func (s *statsdSink) Send(stats *RepairStats) error {
+	return s.write(repairPoints(s.prefix, stats))
+}
+
+// SendCluster sends statistics of completed cluster to StatsD
+func (s *statsdSink) SendCluster(stats *ClusterStats) error {
+	return s.write(clusterPoints(s.prefix, stats))
+}
+
+// write every point as a separate datagram to fit in packet size
+func (s *statsdSink) write(points []point) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, p := range points {
+		kind := "g"
+		if p.timing {
+			kind = "ms"
+		}
+		line := fmt.Sprintf("%s:%s|%s", p.name, strconv.FormatFloat(p.value, 'f', -1, 64), kind)
+		if _, err := s.conn.Write([]byte(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterPoints of completed cluster pass
+func clusterPoints(prefix string, stats *ClusterStats) []point {
+	name := expandPrefix(prefix, stats.Cluster, "", "")
+	return []point{
+		{name: name + ".pass_duration", value: milliseconds(stats.ClusterDuration), timing: true},
+		{name: name + ".last_success", value: float64(stats.LastClusterSuccess.Unix())},
+	}
+}
+
+// clusterStatsOf completed cluster pass
+func clusterStatsOf(stats *RepairStats) *ClusterStats {
+	duration := int64(stats.ClusterAverage) * int64(stats.ClusterCompleted)
+	return &ClusterStats{
+		Cluster:            stats.Cluster,
+		ClusterDuration:    time.Duration(duration),
+		LastClusterSuccess: time.Now(),
+	}
+}
+
+// expandPrefix replaces placeholders, empty ones become allLevels marker so every level keeps its own namespace.
+// Keyspace and table names start with letter or digit and can't collide with the marker
+func expandPrefix(prefix, cluster, keyspace, table string) string {
+	values := map[string]string{"cluster": cluster, "keyspace": keyspace, "table": table}
+	var segments []string
+	for _, segment := range strings.Split(prefix, ".") {
+		segment = placeholder.ReplaceAllStringFunc(segment, func(m string) string {
+			value := values[m[1:len(m)-1]]
+			if value == "" {
+				return allLevels
+			}
+			return unsafeName.ReplaceAllString(value, "_")
+		})
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// repairPoints of fragment with progress of its table, keyspace and cluster, fragment duration is sent when it is known
+func repairPoints(prefix string, stats *RepairStats) []point {
+	table := expandPrefix(prefix, stats.Cluster, stats.Keyspace, stats.Table)
+	keyspace := expandPrefix(prefix, stats.Cluster, stats.Keyspace, "")
+	cluster := expandPrefix(prefix, stats.Cluster, "", "")
+
+	var points []point
+	if stats.Duration > 0 {
+		points = append(points, point{name: table + ".fragment_duration", value: milliseconds(stats.Duration), timing: true})
+	}
+	return append(points, []point{
+		{name: table + ".percent", value: float64(stats.TablePercent)},
+		{name: table + ".completed", value: float64(stats.TableCompleted)},
+		{name: table + ".total", value: float64(stats.TableTotal)},
+		{name: table + ".errors", value: float64(stats.TableErrors)},
+		{name: table + ".timeouts", value: float64(stats.TableTimeouts)},
+		{name: table + ".average", value: milliseconds(stats.TableAverage)},
+		{name: table + ".estimate", value: milliseconds(stats.TableEstimate)},
+		{name: keyspace + ".percent", value: float64(stats.KeyspacePercent)},
+		{name: keyspace + ".completed", value: float64(stats.KeyspaceCompleted)},
+		{name: keyspace + ".total", value: float64(stats.KeyspaceTotal)},
+		{name: keyspace + ".errors", value: float64(stats.KeyspaceErrors)},
+		{name: keyspace + ".timeouts", value: float64(stats.KeyspaceTimeouts)},
+		{name: keyspace + ".estimate", value: milliseconds(stats.KeyspaceEstimate)},
+		{name: cluster + ".percent", value: float64(stats.ClusterPercent)},
+		{name: cluster + ".completed", value: float64(stats.ClusterCompleted)},
+		{name: cluster + ".total", value: float64(stats.ClusterTotal)},
+		{name: cluster + ".errors", value: float64(stats.ClusterErrors)},
+		{name: cluster + ".timeouts", value: float64(stats.ClusterTimeouts)},
+		{name: cluster + ".estimate", value: milliseconds(stats.ClusterEstimate)},
+		{name: cluster + ".rate", value: milliseconds(stats.Rate)},
+	}...)
+}
+
+// untimed copy of failed repair statistics, duration of failed attempt isn't sent
+func untimed(stats *RepairStats) *RepairStats {
+	result := *stats
+	result.Duration = 0
+	return &result
+}
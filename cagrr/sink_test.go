@@ -0,0 +1,211 @@
+package cagrr_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/skbkontur/cagrr/cagrr"
+)
+
+type recordingSink struct {
+	clusters []*ClusterStats
+	mutex    sync.Mutex
+	repairs  []*RepairStats
+}
+
+func (s *recordingSink) Close() {}
+
+func (s *recordingSink) Send(stats *RepairStats) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.repairs = append(s.repairs, stats)
+	return nil
+}
+
+func (s *recordingSink) SendCluster(stats *ClusterStats) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clusters = append(s.clusters, stats)
+	return nil
+}
+
+func (s *recordingSink) received() ([]*RepairStats, []*ClusterStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.repairs, s.clusters
+}
+
+var _ = Describe("Sink", func() {
+	stats := &RepairStats{
+		Cluster:        "main.dc",
+		Keyspace:       "k",
+		Table:          "t",
+		Duration:       1500 * time.Millisecond,
+		TablePercent:   50,
+		KeyspaceErrors: 2,
+		ClusterTotal:   10,
+	}
+
+	Context("StatsD", func() {
+		var (
+			listener net.PacketConn
+			sink     Sink
+		)
+		BeforeEach(func() {
+			var err error
+			listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			sink, err = NewSink(&SinkConfig{Type: "statsd", Address: listener.LocalAddr().String(), Prefix: "ops.{cluster}.{keyspace}.{table}"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			sink.Close()
+			listener.Close()
+		})
+
+		It("should send gauges and timings", func() {
+			Expect(sink.Send(stats)).To(Succeed())
+			var received []string
+			buf := make([]byte, 1024)
+			listener.SetReadDeadline(time.Now().Add(time.Second))
+			for {
+				n, _, err := listener.ReadFrom(buf)
+				if err != nil {
+					break
+				}
+				received = append(received, string(buf[:n]))
+			}
+			Expect(received).To(ContainElement("ops.main_dc.k.t.fragment_duration:1500|ms"))
+			Expect(received).To(ContainElement("ops.main_dc.k.t.percent:50|g"))
+			Expect(received).To(ContainElement("ops.main_dc.k._all.errors:2|g"))
+			Expect(received).To(ContainElement("ops.main_dc._all._all.total:10|g"))
+		})
+	})
+
+	Context("Graphite", func() {
+		var (
+			listener net.Listener
+			lines    chan string
+			sink     Sink
+		)
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			lines = make(chan string, 100)
+			go func(listener net.Listener, lines chan string) {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					go func(conn net.Conn) {
+						defer conn.Close()
+						scanner := bufio.NewScanner(conn)
+						for scanner.Scan() {
+							lines <- scanner.Text()
+						}
+					}(conn)
+				}
+			}(listener, lines)
+			sink, err = NewSink(&SinkConfig{Type: "graphite", Address: listener.Addr().String()})
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			sink.Close()
+			listener.Close()
+		})
+
+		It("should send plaintext lines with default prefix", func() {
+			Expect(sink.Send(stats)).To(Succeed())
+			var line string
+			Eventually(lines).Should(Receive(&line))
+			fields := strings.Fields(line)
+			Expect(fields).To(HaveLen(3))
+			Expect(fields[0]).To(Equal("cagrr.main_dc.k.t.fragment_duration"))
+			Expect(fields[1]).To(Equal("1500"))
+		})
+
+		It("should send cluster statistics", func() {
+			Expect(sink.SendCluster(&ClusterStats{Cluster: "c", ClusterDuration: time.Minute, LastClusterSuccess: time.Unix(100, 0)})).To(Succeed())
+			var line string
+			Eventually(lines).Should(Receive(&line))
+			Expect(line).To(HavePrefix("cagrr.c._all._all.pass_duration 60000 "))
+			Eventually(lines).Should(Receive(&line))
+			Expect(line).To(HavePrefix("cagrr.c._all._all.last_success 100 "))
+		})
+
+		It("should fail when Graphite is unavailable", func() {
+			listener.Close()
+			sink = NewGraphiteSink(listener.Addr().String(), "")
+			Expect(sink.Send(stats)).NotTo(Succeed())
+		})
+	})
+
+	It("should not time failed repairs", func() {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		sink, err := NewSink(&SinkConfig{Type: "statsd", Address: listener.LocalAddr().String()})
+		Expect(err).NotTo(HaveOccurred())
+		defer sink.Close()
+
+		Expect(sink.Send(&RepairStats{Cluster: "c", Keyspace: "k", Table: "t", TableErrors: 1})).To(Succeed())
+		buf := make([]byte, 1024)
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(buf[:n])).To(Equal("cagrr.c.k.t.percent:0|g"))
+	})
+
+	It("should be fed by completed, failed and timed out repairs in background", func() {
+		NewLogger("panic", "")
+		cajrr := newFakeCajrr(`[{"name": "t"}]`, 1)
+		defer cajrr.Close()
+		sink := &recordingSink{}
+		tracker := NewSender(NewTracker(memoryDB{}, NewRegulator(10)), sink)
+		cluster := cajrr.cluster("    repair_timeout: 500ms\n    retry_backoff: 10ms\n")
+		server := NewServer(tracker, []*Cluster{cluster})
+		done := make(chan bool)
+		stopped := make(chan bool)
+		go func(scheduler Scheduler) {
+			defer close(stopped)
+			scheduler.Schedule()
+		}(cluster.TrackIn(tracker).Until(done))
+		defer func() {
+			close(done)
+			Eventually(stopped).Should(BeClosed())
+		}()
+		repairs := func() []*RepairStats {
+			repairs, _ := sink.received()
+			return repairs
+		}
+
+		var repair *Repair
+		Eventually(cajrr.repairs).Should(Receive(&repair))
+		callback(server, repair, "ERROR")
+		Eventually(repairs).Should(HaveLen(1))
+		Expect(repairs()[0].TableErrors).To(Equal(1))
+		Expect(repairs()[0].Duration).To(BeZero())
+
+		Eventually(cajrr.repairs).Should(Receive())
+		Eventually(repairs, "3s").Should(HaveLen(2))
+		Expect(repairs()[1].TableTimeouts).To(Equal(1))
+
+		Eventually(cajrr.repairs, "3s").Should(Receive(&repair))
+		callback(server, repair, "COMPLETE")
+		Eventually(repairs).Should(HaveLen(3))
+		Expect(repairs()[2].TablePercent).To(BeNumerically("==", 100))
+		clusters := func() []*ClusterStats {
+			_, clusters := sink.received()
+			return clusters
+		}
+		Eventually(clusters).Should(HaveLen(1))
+		Expect(clusters()[0].Cluster).To(Equal("c"))
+	})
+})
@@ -1,5 +1,71 @@
 package cagrr
 
+import "time"
+
+const (
+	maxSlices = 1024
+)
+
+// Deadline of table repair: gc_grace_seconds after last completion, zero time when unlimited
+func (t *Table) Deadline(last time.Time) time.Time {
+	if t.GcGrace <= 0 {
+		return time.Time{}
+	}
+	return last.Add(t.GracePeriod())
+}
+
+// IsAtRisk checks that repair of table started now and taking estimated time misses gc_grace_seconds deadline
+func (t *Table) IsAtRisk(last time.Time, estimate time.Duration, now time.Time) bool {
+	deadline := t.Deadline(last)
+	return !deadline.IsZero() && now.Add(estimate).After(deadline)
+}
+
+// GracePeriod of table tombstones
+func (t *Table) GracePeriod() time.Duration {
+	return time.Duration(t.GcGrace) * time.Second
+}
+
+// Merge non-empty settings into table
+func (t *Table) Merge(settings *Table) {
+	if settings.GcGrace > 0 {
+		t.GcGrace = settings.GcGrace
+	}
+	if settings.Size > 0 {
+		t.Size = settings.Size
+	}
+	if settings.Slices > 0 {
+		t.Slices = settings.Slices
+	}
+	if settings.Weight != 0 {
+		t.Weight = settings.Weight
+	}
+	if settings.Interval != "" {
+		t.Interval = settings.Interval
+	}
+	if settings.Parallelism > 0 {
+		t.Parallelism = settings.Parallelism
+	}
+	if settings.Timeout != "" {
+		t.Timeout = settings.Timeout
+	}
+}
+
+// Inherit interval and timeout from keyspace and cluster unless they are set for table
+func (t *Table) Inherit(k *Keyspace, interval, timeout time.Duration) {
+	t.interval = inherit(interval, k.Interval, t.Interval)
+	t.timeout = inherit(timeout, k.Timeout, t.Timeout)
+}
+
+// RepairInterval of table fragments freshness
+func (t *Table) RepairInterval() time.Duration {
+	return t.interval
+}
+
+// RepairTimeout of table fragment
+func (t *Table) RepairTimeout() time.Duration {
+	return t.timeout
+}
+
 // Repairs of table
 func (t *Table) Repairs() []*Repair {
 	return t.repairs
@@ -10,6 +76,19 @@ func (t *Table) Total() int {
 	return t.total
 }
 
+// SliceBy target fragment size, tokens is a number of token ranges in ring
+func (t *Table) SliceBy(fragmentSize int64, tokens int) {
+	if fragmentSize <= 0 || tokens <= 0 || t.Size <= 0 {
+		return
+	}
+	perToken := (t.Size + int64(tokens) - 1) / int64(tokens)
+	slices := (perToken + fragmentSize - 1) / fragmentSize
+	if slices > maxSlices {
+		slices = maxSlices
+	}
+	t.Slices = int(slices)
+}
+
 // SetRepairs to table
 func (t *Table) SetRepairs(repairs []*Repair) {
 	t.repairs = repairs
@@ -19,3 +98,13 @@ func (t *Table) SetRepairs(repairs []*Repair) {
 func (t *Table) SetTotal(total int) {
 	t.total = total
 }
+
+// inherit returns the last valid duration of given ones overriding value
+func inherit(value time.Duration, overrides ...string) time.Duration {
+	for _, o := range overrides {
+		if d, err := time.ParseDuration(o); err == nil && d > 0 {
+			value = d
+		}
+	}
+	return value
+}
@@ -1,6 +1,8 @@
 package cagrr_test
 
 import (
+	"time"
+
 	. "github.com/skbkontur/cagrr/cagrr"
 
 	. "github.com/onsi/ginkgo"
@@ -38,4 +40,94 @@ var _ = Describe("Table", func() {
 		})
 
 	})
+	Context("deadline", func() {
+		It("should be unlimited without gc_grace_seconds", func() {
+			Expect(table.Deadline(time.Now()).IsZero()).To(BeTrue())
+		})
+
+		It("should be gc_grace_seconds after last repair", func() {
+			last := time.Now()
+			table.GcGrace = 864000
+			Expect(table.Deadline(last)).To(BeTemporally("==", last.Add(time.Hour*240)))
+		})
+
+		It("shouldn't be at risk without gc_grace_seconds", func() {
+			Expect(table.IsAtRisk(time.Now().Add(-time.Hour*24*365), time.Hour, time.Now())).To(BeFalse())
+		})
+
+		It("should be at risk when estimated repair ends after deadline", func() {
+			now := time.Now()
+			table.GcGrace = 3600
+			Expect(table.IsAtRisk(now.Add(-time.Minute*50), time.Minute*10, now)).To(BeFalse())
+			Expect(table.IsAtRisk(now.Add(-time.Minute*50), time.Minute*11, now)).To(BeTrue())
+			Expect(table.IsAtRisk(now.Add(-time.Minute*70), 0, now)).To(BeTrue())
+		})
+
+		It("should take gc_grace_seconds from settings", func() {
+			table.GcGrace = 864000
+			table.Merge(&Table{Name: "table", GcGrace: 3600})
+			Expect(table.GracePeriod()).To(Equal(time.Hour))
+		})
+
+		It("shouldn't reset gc_grace_seconds by empty settings", func() {
+			table.GcGrace = 3600
+			table.Merge(&Table{Name: "table"})
+			Expect(table.GcGrace).To(Equal(3600))
+		})
+	})
+	Context("slicing", func() {
+		BeforeEach(func() {
+			table.Slices = 1
+			table.Size = 10 * 1024
+		})
+
+		It("should keep slices without fragment size", func() {
+			table.SliceBy(0, 4)
+			Expect(table.Slices).To(Equal(1))
+		})
+
+		It("should slice token ranges by fragment size", func() {
+			table.SliceBy(1024, 4)
+			Expect(table.Slices).To(Equal(3))
+		})
+
+		It("should limit number of slices", func() {
+			table.SliceBy(1, 1)
+			Expect(table.Slices).To(Equal(1024))
+		})
+
+		It("should prefer configured slices", func() {
+			table.SliceBy(1024, 4)
+			table.Merge(&Table{Name: "table", Slices: 8})
+			Expect(table.Slices).To(Equal(8))
+		})
+	})
+	Context("inheritance", func() {
+		var keyspace *Keyspace
+		BeforeEach(func() {
+			keyspace = &Keyspace{Name: "keyspace"}
+		})
+
+		It("should inherit cluster settings", func() {
+			table.Inherit(keyspace, time.Hour, time.Minute)
+			Expect(table.RepairInterval()).To(Equal(time.Hour))
+			Expect(table.RepairTimeout()).To(Equal(time.Minute))
+		})
+
+		It("should inherit keyspace settings", func() {
+			keyspace.Interval = "24h"
+			table.Inherit(keyspace, time.Hour, time.Minute)
+			Expect(table.RepairInterval()).To(Equal(time.Hour * 24))
+			Expect(table.RepairTimeout()).To(Equal(time.Minute))
+		})
+
+		It("should prefer table settings", func() {
+			keyspace.Interval = "24h"
+			keyspace.Timeout = "2m"
+			table.Merge(&Table{Name: "table", Interval: "720h", Timeout: "3m"})
+			table.Inherit(keyspace, time.Hour, time.Minute)
+			Expect(table.RepairInterval()).To(Equal(time.Hour * 720))
+			Expect(table.RepairTimeout()).To(Equal(time.Minute * 3))
+		})
+	})
 })
@@ -30,6 +30,22 @@ func (t *Track) Complete(duration time.Duration, err bool) (int, int, int, time.
 	return t.Total, t.Count, t.Errors, t.Average, t.Percent, t.Estimate, t.Duration
 }
 
+// Forget counts of child track which progress is reset
+func (t *Track) Forget(child *Track) {
+	t.Count = nonNegative(t.Count - child.Count)
+	t.Errors = nonNegative(t.Errors - child.Errors)
+	t.Timeouts = nonNegative(t.Timeouts - child.Timeouts)
+	t.Poisoned = nonNegative(t.Poisoned - child.Poisoned)
+	if t.Total > 0 {
+		t.Percent = t.percent()
+	}
+}
+
+// IsOver checks that every repair item is either completed or poisoned
+func (t *Track) IsOver() bool {
+	return t.Completed || (t.Total > 0 && t.Count+t.Poisoned >= t.Total)
+}
+
 // IsRepaired is check for repair completeness
 func (t *Track) IsRepaired(threshold time.Duration) bool {
 	return t.Completed && !t.IsSpoiled(threshold)
@@ -49,6 +65,18 @@ func (t *Track) IsSpoiled(threshold time.Duration) bool {
 	return duration > threshold
 }
 
+// TimedOut counts repair lost by timeout
+func (t *Track) TimedOut() int {
+	t.Timeouts++
+	return t.Timeouts
+}
+
+// Poison counts repair which ran out of attempts
+func (t *Track) Poison() int {
+	t.Poisoned++
+	return t.Poisoned
+}
+
 // Skip track
 func (t *Track) Skip() {
 	t.Count++
@@ -60,6 +88,8 @@ func (t *Track) Start(total int) {
 	t.Started = time.Now()
 	t.Count = 0
 	t.Errors = 0
+	t.Timeouts = 0
+	t.Poisoned = 0
 	t.Total = total
 	t.Completed = false
 }
@@ -80,3 +110,10 @@ func (t *Track) estimate(average time.Duration) time.Duration {
 func (t *Track) percent() float32 {
 	return (100 * float32(t.Count) / float32(t.Total))
 }
+
+func nonNegative(value int) int {
+	if value < 0 {
+		return 0
+	}
+	return value
+}
@@ -57,6 +57,33 @@ var _ = Describe("Track", func() {
 				Expect(track.Percent).To(BeNumerically("==", 100))
 			})
 		})
+		Context("timeouts", func() {
+			It("should count timed out repairs", func() {
+				track.TimedOut()
+				Expect(track.TimedOut()).To(Equal(2))
+			})
+			It("should be reset on start", func() {
+				track.TimedOut()
+				track.Start(5)
+				Expect(track.Timeouts).To(Equal(0))
+			})
+		})
+		Context("poisoned", func() {
+			BeforeEach(func() {
+				err := false
+				track.Complete(time.Duration(0), err)
+				track.Complete(time.Duration(0), err)
+				track.Complete(time.Duration(0), err)
+				track.Complete(time.Duration(0), err)
+				track.Poison()
+			})
+			It("should be over", func() {
+				Expect(track.IsOver()).To(BeTrue())
+			})
+			It("shouldn't be completed", func() {
+				Expect(track.Completed).To(BeFalse())
+			})
+		})
 		Context("completed", func() {
 			BeforeEach(func() {
 				err := false
@@ -3,12 +3,15 @@ package cagrr
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	tableName  = "repairs"
-	timeFormat = "2006-01-02 15:04:05 -0700 -07"
+	controlName = "control"
+	pausedKey   = "paused"
+	tableName   = "repairs"
+	timeFormat  = "2006-01-02 15:04:05 -0700 -07"
 )
 
 // NewTracker created new progress tracker
@@ -20,77 +23,122 @@ func NewTracker(db DB, r Regulator) Tracker {
 	}
 }
 
+// Children reads tracks one level below cluster, keyspace or table by their names
+func (t *tracker) Children(keys ...string) map[string]*Track {
+	result := make(map[string]*Track)
+	prefix := t.db.CreateKey(append(keys, "")...)
+	names, err := t.db.ListKeys(tableName, prefix)
+	if err != nil {
+		log.WithError(err).Warn("Tracks list error")
+		return result
+	}
+	for _, name := range names {
+		child := strings.TrimPrefix(name, prefix)
+		if child == "" || strings.Contains(child, t.db.CreateKey("", "")) {
+			continue
+		}
+		result[child] = t.readTrack(name)
+	}
+	return result
+}
+
 // Complete repair and returns statistics
 func (t *tracker) Complete(cluster, keyspace, table string, id int, err bool) *RepairStats {
+	return t.complete(cluster, keyspace, table, id, err, false)
+}
+
+func (t *tracker) HasErrors(vars ...string) bool {
+	key := t.db.CreateKey(vars...)
+	track := t.readTrack(key)
+	return track.Errors > 0
+}
+
+// IsCompleted check fragment completion
+func (t *tracker) IsCompleted(cluster, keyspace, table string, id int, threshold time.Duration) bool {
+	key := t.db.CreateKey(cluster, keyspace, table, strconv.Itoa(id))
+
+	track := t.readTrack(key)
+
+	return track.IsRepaired(threshold)
+}
+
+// IsPaused reads persisted pause state of cluster
+func (t *tracker) IsPaused(cluster string) bool {
+	key := t.db.CreateKey(pausedKey, cluster)
+	value := t.db.ReadValue(controlName, key)
+	return string(value) == "true"
+}
+
+// Poison marks repair which ran out of attempts
+func (t *tracker) Poison(cluster, keyspace, table string, id int) *RepairStats {
 	ck, kk, tk, rk := t.keys(cluster, keyspace, table, id)
 
 	track := t.readTrack(rk)
-	_, _, _, _, _, _, rd := track.Complete(time.Duration(0), err)
-	rate := t.regulator.LimitRateTo(cluster, rd)
-	track.Rate = rate
+	track.Poison()
 	t.writeTrack(rk, track)
 
 	track = t.readTrack(tk)
-	tt, tc, terr, ta, tp, te, td := track.Complete(rd, err)
-	track.Rate = rate
+	tp := track.Poison()
+	terr := track.Errors
 	t.writeTrack(tk, track)
 
 	track = t.readTrack(kk)
-	kt, kc, kerr, ka, kp, ke, kd := track.Complete(rd, err)
-	track.Rate = rate
+	kp := track.Poison()
+	kerr := track.Errors
 	t.writeTrack(kk, track)
 
 	track = t.readTrack(ck)
-	ct, cc, cerr, ca, cp, ce, cd := track.Complete(rd, err)
-	track.Rate = rate
+	cp := track.Poison()
+	cerr := track.Errors
 	t.writeTrack(ck, track)
 
 	return &RepairStats{
-		Cluster:            cluster,
-		Keyspace:           keyspace,
-		Table:              table,
-		ID:                 id,
-		Duration:           rd,
-		Rate:               rate,
-		TableTotal:         tt,
-		TableCompleted:     tc,
-		TableErrors:        terr,
-		TablePercent:       tp,
-		TableDuration:      td,
-		TableAverage:       ta,
-		TableEstimate:      te,
-		KeyspaceTotal:      kt,
-		KeyspaceCompleted:  kc,
-		KeyspaceErrors:     kerr,
-		KeyspacePercent:    kp,
-		KeyspaceDuration:   kd,
-		KeyspaceAverage:    ka,
-		KeyspaceEstimate:   ke,
-		ClusterTotal:       ct,
-		ClusterCompleted:   cc,
-		ClusterErrors:      cerr,
-		ClusterPercent:     cp,
-		ClusterDuration:    cd,
-		ClusterAverage:     ca,
-		ClusterEstimate:    ce,
-		LastClusterSuccess: track.Finished,
+		Cluster:          cluster,
+		Keyspace:         keyspace,
+		Table:            table,
+		ID:               id,
+		TableErrors:      terr,
+		TablePoisoned:    tp,
+		KeyspaceErrors:   kerr,
+		KeyspacePoisoned: kp,
+		ClusterErrors:    cerr,
+		ClusterPoisoned:  cp,
 	}
-
 }
 
-func (t *tracker) HasErrors(vars ...string) bool {
+// Read track of cluster, keyspace, table or fragment
+func (t *tracker) Read(vars ...string) *Track {
 	key := t.db.CreateKey(vars...)
-	track := t.readTrack(key)
-	return track.Errors > 0
+	return t.readTrack(key)
 }
 
-// IsCompleted check fragment completion
-func (t *tracker) IsCompleted(cluster, keyspace, table string, id int, threshold time.Duration) bool {
-	key := t.db.CreateKey(cluster, keyspace, table, strconv.Itoa(id))
+// Reset forgets stored progress of cluster, keyspace, table or fragment with everything below it,
+// its counts are taken from parent tracks while their last completion is kept
+func (t *tracker) Reset(keys ...string) error {
+	key := t.db.CreateKey(keys...)
+	t.forget(t.readTrack(key), keys)
+	t.db.Delete(tableName, key)
+	return t.db.DeleteTree(tableName, t.db.CreateKey(key, ""))
+}
 
+// Restart track of keyspace or table when its pass is over, its counts are taken from parent tracks
+// so that the restarted pass completes them again
+func (t *tracker) Restart(total int, keys ...string) {
+	key := t.db.CreateKey(keys...)
 	track := t.readTrack(key)
+	if !track.IsNew() && !track.IsOver() {
+		return
+	}
+	t.forget(track, keys)
+	track.Start(total)
+	t.writeTrack(key, track)
+}
 
-	return track.IsRepaired(threshold)
+// SetPaused persists pause state of cluster
+func (t *tracker) SetPaused(cluster string, paused bool) {
+	key := t.db.CreateKey(pausedKey, cluster)
+	value := strconv.FormatBool(paused)
+	t.db.WriteValue(controlName, key, []byte(value))
 }
 
 func (t *tracker) Skip(cluster, keyspace, table string, id int) {
@@ -130,8 +178,100 @@ func (t *tracker) StartTable(cluster, keyspace, table string, total int) {
 	t.start(key, total)
 }
 
-func (t *tracker) TrackError(cluster, keyspace, table string, id int) {
-	t.Complete(cluster, keyspace, table, id, true)
+func (t *tracker) TrackError(cluster, keyspace, table string, id int) *RepairStats {
+	return t.Complete(cluster, keyspace, table, id, true)
+}
+
+// TrackTimeout counts repair lost by timeout as an error
+func (t *tracker) TrackTimeout(cluster, keyspace, table string, id int) *RepairStats {
+	return t.complete(cluster, keyspace, table, id, true, true)
+}
+
+func (t *tracker) complete(cluster, keyspace, table string, id int, err, timeout bool) *RepairStats {
+	ck, kk, tk, rk := t.keys(cluster, keyspace, table, id)
+
+	track := t.readTrack(rk)
+	_, _, _, _, _, _, rd := track.Complete(time.Duration(0), err)
+	rate := t.regulator.LimitRateTo(cluster, rd)
+	track.Rate = rate
+	if timeout {
+		track.TimedOut()
+	}
+	t.writeTrack(rk, track)
+
+	track = t.readTrack(tk)
+	tt, tc, terr, ta, tp, te, td := track.Complete(rd, err)
+	track.Rate = rate
+	if timeout {
+		track.TimedOut()
+	}
+	tto := track.Timeouts
+	t.writeTrack(tk, track)
+
+	track = t.readTrack(kk)
+	kt, kc, kerr, ka, kp, ke, kd := track.Complete(rd, err)
+	track.Rate = rate
+	if timeout {
+		track.TimedOut()
+	}
+	kto := track.Timeouts
+	t.writeTrack(kk, track)
+
+	track = t.readTrack(ck)
+	ct, cc, cerr, ca, cp, ce, cd := track.Complete(rd, err)
+	track.Rate = rate
+	if timeout {
+		track.TimedOut()
+	}
+	cto := track.Timeouts
+	t.writeTrack(ck, track)
+
+	return &RepairStats{
+		Cluster:            cluster,
+		Keyspace:           keyspace,
+		Table:              table,
+		ID:                 id,
+		Duration:           rd,
+		Rate:               rate,
+		TableTotal:         tt,
+		TableCompleted:     tc,
+		TableErrors:        terr,
+		TableTimeouts:      tto,
+		TablePercent:       tp,
+		TableDuration:      td,
+		TableAverage:       ta,
+		TableEstimate:      te,
+		KeyspaceTotal:      kt,
+		KeyspaceCompleted:  kc,
+		KeyspaceErrors:     kerr,
+		KeyspaceTimeouts:   kto,
+		KeyspacePercent:    kp,
+		KeyspaceDuration:   kd,
+		KeyspaceAverage:    ka,
+		KeyspaceEstimate:   ke,
+		ClusterTotal:       ct,
+		ClusterCompleted:   cc,
+		ClusterErrors:      cerr,
+		ClusterTimeouts:    cto,
+		ClusterPercent:     cp,
+		ClusterDuration:    cd,
+		ClusterAverage:     ca,
+		ClusterEstimate:    ce,
+		LastClusterSuccess: track.Finished,
+	}
+}
+
+// forget counts of track given by keys in its parent tracks
+func (t *tracker) forget(forgotten *Track, keys []string) {
+	for i := len(keys) - 1; i > 0; i-- {
+		parentKey := t.db.CreateKey(keys[:i]...)
+		parent := t.readTrack(parentKey)
+		if parent.IsNew() {
+			continue
+		}
+		parent.Forget(forgotten)
+		t.writeTrack(parentKey, parent)
+	}
 }
 
 func (t *tracker) readTrack(key string) *Track {
@@ -156,7 +296,7 @@ func (t *tracker) keys(cluster, keyspace, table string, row int) (string, string
 
 func (t *tracker) start(key string, total int) {
 	track := t.readTrack(key)
-	if track.IsNew() || track.Completed {
+	if track.IsNew() || track.IsOver() {
 		track.Start(total)
 		t.writeTrack(key, track)
 	}
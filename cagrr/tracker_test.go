@@ -0,0 +1,190 @@
+package cagrr_test
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type memoryDB map[string][]byte
+
+// memoryLock guards memory databases shared by schedulers and tests
+var memoryLock sync.Mutex
+
+func (m memoryDB) Close() {}
+
+func (m memoryDB) CreateKey(vars ...string) string {
+	return strings.Join(vars, "/")
+}
+
+func (m memoryDB) Delete(table, key string) {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	delete(m, m.CreateKey(table, key))
+}
+
+func (m memoryDB) DeleteTree(table, prefix string) error {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	for key := range m {
+		if strings.HasPrefix(key, m.CreateKey(table, prefix)) {
+			delete(m, key)
+		}
+	}
+	return nil
+}
+
+func (m memoryDB) ListKeys(table, prefix string) ([]string, error) {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	var result []string
+	for key := range m {
+		if strings.HasPrefix(key, m.CreateKey(table, prefix)) {
+			result = append(result, strings.TrimPrefix(key, m.CreateKey(table, "")))
+		}
+	}
+	return result, nil
+}
+
+func (m memoryDB) Ping() error {
+	return nil
+}
+
+func (m memoryDB) ReadValue(table, key string) []byte {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	return m[m.CreateKey(table, key)]
+}
+
+func (m memoryDB) WriteValue(table, key string, value []byte) error {
+	memoryLock.Lock()
+	defer memoryLock.Unlock()
+	m[m.CreateKey(table, key)] = value
+	return nil
+}
+
+var _ = Describe("Tracker", func() {
+	var tracker Tracker
+	BeforeEach(func() {
+		tracker = NewTracker(memoryDB{}, NewRegulator(10))
+		for _, cluster := range []string{"c", "cc"} {
+			tracker.StartCluster(cluster, 2)
+			tracker.StartKeyspace(cluster, "k", 2)
+			tracker.StartTable(cluster, "k", "t1", 1)
+			tracker.StartTable(cluster, "k", "t2", 1)
+			tracker.Start(cluster, "k", "t1", 1)
+			tracker.Start(cluster, "k", "t2", 1)
+			tracker.Complete(cluster, "k", "t1", 1, false)
+			tracker.Complete(cluster, "k", "t2", 1, false)
+		}
+	})
+
+	It("should read completed fragments", func() {
+		Expect(tracker.IsCompleted("c", "k", "t1", 1, time.Hour)).To(BeTrue())
+		Expect(tracker.Read("c").Count).To(Equal(2))
+	})
+
+	It("should read children of hierarchy level", func() {
+		Expect(tracker.Children("c")).To(HaveLen(1))
+		Expect(tracker.Children("c", "k")).To(HaveKey("t1"))
+		Expect(tracker.Children("c", "k")).To(HaveLen(2))
+		Expect(tracker.Children("c", "k", "t1")).To(HaveKey("1"))
+		Expect(tracker.Children("c", "k", "t1")["1"].Completed).To(BeTrue())
+	})
+
+	Context("reset", func() {
+		It("should forget table and its fragments", func() {
+			Expect(tracker.Reset("c", "k", "t1")).To(Succeed())
+			Expect(tracker.Read("c", "k", "t1").IsNew()).To(BeTrue())
+			Expect(tracker.IsCompleted("c", "k", "t1", 1, time.Hour)).To(BeFalse())
+			Expect(tracker.IsCompleted("c", "k", "t2", 1, time.Hour)).To(BeTrue())
+		})
+
+		It("should forget single fragment", func() {
+			Expect(tracker.Reset("c", "k", "t1", "1")).To(Succeed())
+			Expect(tracker.IsCompleted("c", "k", "t1", 1, time.Hour)).To(BeFalse())
+			Expect(tracker.Read("c", "k", "t1").Completed).To(BeTrue())
+			Expect(tracker.Read("c", "k", "t1").Count).To(Equal(0))
+		})
+
+		It("should take counts of forgotten table from keyspace and cluster", func() {
+			Expect(tracker.Reset("c", "k", "t1")).To(Succeed())
+			for _, keys := range [][]string{{"c"}, {"c", "k"}} {
+				track := tracker.Read(keys...)
+				Expect(track.Count).To(Equal(1))
+				Expect(track.Total).To(Equal(2))
+				Expect(track.Percent).To(BeNumerically("==", 50))
+			}
+			Expect(tracker.Read("cc").Count).To(Equal(2))
+		})
+
+		It("should restart parents by the next pass and count every fragment once", func() {
+			Expect(tracker.Reset("c", "k", "t1")).To(Succeed())
+			tracker.StartCluster("c", 2)
+			tracker.StartKeyspace("c", "k", 2)
+			tracker.StartTable("c", "k", "t1", 1)
+			tracker.StartTable("c", "k", "t2", 1)
+			tracker.Skip("c", "k", "t2", 1)
+			tracker.Start("c", "k", "t1", 1)
+			stats := tracker.Complete("c", "k", "t1", 1, false)
+			Expect(stats.ClusterCompleted).To(Equal(2))
+			Expect(stats.ClusterPercent).To(BeNumerically("==", 100))
+		})
+
+		It("should forget whole cluster only", func() {
+			Expect(tracker.Reset("c")).To(Succeed())
+			Expect(tracker.Read("c").IsNew()).To(BeTrue())
+			Expect(tracker.IsCompleted("c", "k", "t2", 1, time.Hour)).To(BeFalse())
+			Expect(tracker.Read("cc").IsNew()).To(BeFalse())
+			Expect(tracker.IsCompleted("cc", "k", "t2", 1, time.Hour)).To(BeTrue())
+		})
+	})
+
+	Context("restart", func() {
+		It("should complete parents again by pass of single table", func() {
+			tracker.Restart(1, "c", "k", "t1")
+			Expect(tracker.Read("c").Count).To(Equal(1))
+			Expect(tracker.Read("c", "k", "t1").Count).To(Equal(0))
+
+			tracker.Skip("c", "k", "t1", 1)
+			for _, keys := range [][]string{{"c"}, {"c", "k"}} {
+				track := tracker.Read(keys...)
+				Expect(track.Count).To(Equal(2))
+				Expect(track.Percent).To(BeNumerically("==", 100))
+			}
+		})
+
+		It("should keep track of pass in progress", func() {
+			tracker.StartTable("c", "k", "t3", 2)
+			tracker.Skip("c", "k", "t3", 1)
+			tracker.Restart(2, "c", "k", "t3")
+			Expect(tracker.Read("c", "k", "t3").Count).To(Equal(1))
+			Expect(tracker.Read("c").Count).To(Equal(3))
+		})
+	})
+
+	Context("pause", func() {
+		It("should not be paused by default", func() {
+			Expect(tracker.IsPaused("c")).To(BeFalse())
+		})
+
+		It("should persist pause of cluster", func() {
+			tracker.SetPaused("c", true)
+			Expect(tracker.IsPaused("c")).To(BeTrue())
+			Expect(tracker.IsPaused("cc")).To(BeFalse())
+
+			tracker.SetPaused("c", false)
+			Expect(tracker.IsPaused("c")).To(BeFalse())
+		})
+
+		It("should not mix pause with progress", func() {
+			tracker.SetPaused("c", true)
+			Expect(tracker.Children("c")).To(HaveLen(1))
+		})
+	})
+})
@@ -1,7 +1,13 @@
 package cagrr
 
 import (
+	"io"
+	"math/big"
+	"math/rand"
+	"net"
 	"net/http"
+	"regexp"
+	"sync"
 	"time"
 
 	redis "gopkg.in/redis.v5"
@@ -9,17 +15,63 @@ import (
 	"github.com/hashicorp/consul/api"
 )
 
+// AuditEntry records control action requested over HTTP
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Remote   string    `json:"remote"`
+	Action   string    `json:"action"`
+	Cluster  string    `json:"cluster,omitempty"`
+	Keyspace string    `json:"keyspace,omitempty"`
+	Table    string    `json:"table,omitempty"`
+	Fragment string    `json:"fragment,omitempty"`
+	Force    bool      `json:"force,omitempty"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
 // Cluster contains configuration of cluster item
 type Cluster struct {
-	ID        int
-	Name      string      `yaml:"name"`
-	Interval  string      `yaml:"interval"`
-	Keyspaces []*Keyspace `yaml:"keyspaces"`
-	Host      string
-	Port      int
-	done      chan bool
-	regulator Regulator
-	tracker   Tracker
+	ID                int
+	Name              string    `yaml:"name"`
+	Interval          string    `yaml:"interval"`
+	Crontab           string    `yaml:"schedule"`
+	Parallelism       int       `yaml:"parallelism"`
+	Windows           []string  `yaml:"windows"`
+	MaxRepairsPerNode int       `yaml:"max_repairs_per_node"`
+	RepairTimeout     string    `yaml:"repair_timeout"`
+	MaxAttempts       int       `yaml:"max_attempts"`
+	RetryBackoff      string    `yaml:"retry_backoff"`
+	FragmentSize      int64     `yaml:"fragment_size"`
+	Keyspaces         Keyspaces `yaml:"keyspaces"`
+	ExcludeKeyspaces  []string  `yaml:"exclude_keyspaces"`
+	Host              string
+	Port              int
+	awaited           map[string]int
+	callback          string
+	cancels           chan *scope
+	cron              Cron
+	done              chan bool
+	events            Publisher
+	exclude           []*regexp.Regexp
+	manual            bool
+	mutex             sync.Mutex
+	once              sync.Once
+	path              string
+	paused            bool
+	pending           []*Repair
+	poisoned          []*Repair
+	progress          func(*Repair, string)
+	reaper            *time.Ticker
+	regulator         Regulator
+	retrier           Retrier
+	running           map[string]*Repair
+	source            string
+	statuses          chan *RepairStatus
+	tracker           Tracker
+	triggers          chan *pass
+	updates           chan *Cluster
+	wakeup            chan bool
+	windows           []Window
 }
 
 // ClusterStats for logging
@@ -31,35 +83,150 @@ type ClusterStats struct {
 
 // Config is a configuration file struct
 type Config struct {
-	BufferLength int        `yaml:"buffer"`
-	ConsulHost   string     `yaml:"consul_host"`
-	Clusters     []*Cluster `yaml:"clusters"`
+	BufferLength int           `yaml:"buffer"`
+	ConsulHost   string        `yaml:"consul_host"`
+	Include      []string      `yaml:"include"`
+	Metrics      []*SinkConfig `yaml:"metrics"`
+	Clusters     []*Cluster    `yaml:"clusters"`
+	files        map[string]map[string]int
+	sources      map[string]string
+}
+
+// ConfigError is a configuration problem found at given line and path
+type ConfigError struct {
+	File    string
+	Line    int
+	Path    string
+	Message string
+}
+
+// ConfigErrors are all problems of configuration
+type ConfigErrors []*ConfigError
+
+// DeadlineStats for logging tables at risk of missing gc_grace_seconds
+type DeadlineStats struct {
+	Cluster  string
+	Keyspace string
+	Table    string
+	GcGrace  time.Duration
+	Deadline time.Time
+	Estimate time.Duration
+	Margin   time.Duration
+}
+
+// Event of repair lifecycle
+type Event struct {
+	Type     string       `json:"type"`
+	Time     time.Time    `json:"time"`
+	Cluster  string       `json:"cluster"`
+	Keyspace string       `json:"keyspace,omitempty"`
+	Table    string       `json:"table,omitempty"`
+	ID       int          `json:"id,omitempty"`
+	Message  string       `json:"message,omitempty"`
+	Stats    *RepairStats `json:"stats,omitempty"`
 }
 
 // Fragment of Token range for repair
 type Fragment struct {
 	ID       int `json:"id"`
 	Endpoint string
+	Replicas []string
 	Start    string
 	End      string
 }
 
+// Health of cagrr and its dependencies
+type Health struct {
+	Status string         `json:"status"`
+	Checks []*HealthCheck `json:"checks"`
+}
+
+// HealthCheck is a result of dependency check
+type HealthCheck struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
 // Keyspace contains keyspace repair schedule description
 type Keyspace struct {
-	Name   string `yaml:"name"`
-	tables []*Table
-	total  int
+	Name          string   `yaml:"name"`
+	Interval      string   `yaml:"interval"`
+	Slices        int      `yaml:"slices"`
+	Parallelism   int      `yaml:"parallelism"`
+	Timeout       string   `yaml:"timeout"`
+	Windows       []string `yaml:"windows"`
+	IncludeTables []string `yaml:"include_tables"`
+	ExcludeTables []string `yaml:"exclude_tables"`
+	TableSettings []*Table `yaml:"tables"`
+	exclude       []*regexp.Regexp
+	include       []*regexp.Regexp
+	tables        []*Table
+	total         int
+	windows       []Window
 }
 
-// Repair object
-type Repair struct {
-	ID       int    `json:"id"`
-	Cluster  string `json:"cluster"`
+// Keyspaces is a list of keyspaces, "*" stands for all keyspaces of cluster
+type Keyspaces []*Keyspace
+
+// Override of configuration value from environment or command line, keys are
+// consul_host, buffer, cluster.<name>.host and cluster.<name>.port
+type Override struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Plan of cluster repairs
+type Plan struct {
+	Cluster  string        `json:"cluster"`
+	Total    int           `json:"total"`
+	Skipped  int           `json:"skipped"`
+	Estimate time.Duration `json:"estimate"`
+	Items    []*PlanItem   `json:"items"`
+}
+
+// PlanItem is a repair fragment of plan
+type PlanItem struct {
 	Keyspace string `json:"keyspace"`
 	Table    string `json:"table"`
+	ID       int    `json:"id"`
 	Endpoint string `json:"endpoint"`
 	Start    string `json:"start"`
 	End      string `json:"end"`
+	Skip     bool   `json:"skip"`
+}
+
+// Progress is a node of repair progress hierarchy: cluster, keyspace, table or fragment
+type Progress struct {
+	Name     string       `json:"name"`
+	Track    *Track       `json:"track"`
+	Last     *RepairStats `json:"last,omitempty"`
+	Source   string       `json:"source,omitempty"`
+	Children []*Progress  `json:"children,omitempty"`
+}
+
+// Repair object
+type Repair struct {
+	ID       int      `json:"id"`
+	Cluster  string   `json:"cluster"`
+	Keyspace string   `json:"keyspace"`
+	Table    string   `json:"table"`
+	Endpoint string   `json:"endpoint"`
+	Replicas []string `json:"-"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Attempt  int      `json:"attempt"`
+	Callback string   `json:"callback,omitempty"`
+	atRisk   bool
+	attempts int
+	deadline time.Time
+	keyspace *Keyspace
+	retry    time.Time
+	started  time.Time
+	table    *Table
+	weight   float32
 }
 
 // RepairStats for logging
@@ -73,6 +240,8 @@ type RepairStats struct {
 	TableTotal         int
 	TableCompleted     int
 	TableErrors        int
+	TableTimeouts      int
+	TablePoisoned      int
 	TablePercent       float32
 	TableDuration      time.Duration
 	TableAverage       time.Duration
@@ -80,6 +249,8 @@ type RepairStats struct {
 	KeyspaceTotal      int
 	KeyspaceCompleted  int
 	KeyspaceErrors     int
+	KeyspaceTimeouts   int
+	KeyspacePoisoned   int
 	KeyspacePercent    float32
 	KeyspaceDuration   time.Duration
 	KeyspaceAverage    time.Duration
@@ -87,6 +258,8 @@ type RepairStats struct {
 	ClusterTotal       int
 	ClusterCompleted   int
 	ClusterErrors      int
+	ClusterTimeouts    int
+	ClusterPoisoned    int
 	ClusterPercent     float32
 	ClusterDuration    time.Duration
 	ClusterAverage     time.Duration
@@ -101,14 +274,52 @@ type RepairStatus struct {
 	Type    string
 }
 
+// Setting is effective configuration value with its source
+type Setting struct {
+	Path   string
+	Value  string
+	Source string
+}
+
+// SinkConfig is StatsD or Graphite receiver of repair statistics,
+// prefix may include {cluster}, {keyspace} and {table} placeholders
+type SinkConfig struct {
+	Type    string `yaml:"type"`
+	Address string `yaml:"address"`
+	Prefix  string `yaml:"prefix"`
+}
+
+// Status of stored repair progress of cluster, keyspace or table
+type Status struct {
+	Cluster   string        `json:"cluster"`
+	Keyspace  string        `json:"keyspace,omitempty"`
+	Table     string        `json:"table,omitempty"`
+	Percent   float32       `json:"percent"`
+	Completed int           `json:"completed"`
+	Total     int           `json:"total"`
+	Errors    int           `json:"errors"`
+	Average   time.Duration `json:"average"`
+	Estimate  time.Duration `json:"estimate"`
+	Finished  time.Time     `json:"finished"`
+	Interval  time.Duration `json:"interval"`
+	Stale     bool          `json:"stale"`
+	Source    string        `json:"source,omitempty"`
+}
+
 // Table contains column families to repair
 type Table struct {
-	Name    string  `yaml:"name"`
-	Size    int64   `yaml:"size"`
-	Slices  int     `yaml:"slices"`
-	Weight  float32 `yaml:"weight"`
-	repairs []*Repair
-	total   int
+	Name        string  `yaml:"name"`
+	Size        int64   `yaml:"size"`
+	Slices      int     `yaml:"slices"`
+	Weight      float32 `yaml:"weight"`
+	GcGrace     int     `yaml:"gc_grace_seconds" json:"gcGraceSeconds"`
+	Interval    string  `yaml:"interval" json:"-"`
+	Parallelism int     `yaml:"parallelism" json:"-"`
+	Timeout     string  `yaml:"timeout" json:"-"`
+	interval    time.Duration
+	repairs     []*Repair
+	timeout     time.Duration
+	total       int
 }
 
 // Token represents cassandra key range
@@ -125,6 +336,8 @@ type Track struct {
 	Completed bool
 	Count     int
 	Errors    int
+	Timeouts  int
+	Poisoned  int
 	Total     int
 	Percent   float32
 	Duration  time.Duration
@@ -135,15 +348,78 @@ type Track struct {
 	Started   time.Time
 }
 
+type byKey []Override
+
+type byNumber []string
+
+type byPriority []*Repair
+
+type closedWindow struct{}
+
 type consulDB struct {
 	db *api.Client
 }
 
+type cron struct {
+	minutes    uint64
+	hours      uint64
+	days       uint64
+	months     uint64
+	weekdays   uint64
+	anyDay     bool
+	anyWeekday bool
+	location   *time.Location
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+type graphiteSink struct {
+	address string
+	conn    net.Conn
+	mutex   sync.Mutex
+	prefix  string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
 type logger struct {
 	err    error
 	fields map[string]interface{}
 }
 
+type metrics struct {
+	Tracker
+	buckets    []float64
+	counters   map[string]map[string]float64
+	histograms map[string]*histogram
+	mutex      sync.Mutex
+	regulator  Regulator
+}
+
+type pass struct {
+	scope
+	force bool
+	reply chan error
+}
+
+type point struct {
+	name   string
+	value  float64
+	timing bool
+}
+
+type publisher struct {
+	mutex       sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
 type queue struct {
 	nodes []time.Duration
 	size  int
@@ -154,18 +430,86 @@ type redisDB struct {
 }
 
 type regulator struct {
+	mutex  sync.Mutex
 	queues map[string]DurationQueue
 	size   int
 }
 
+type retrier struct {
+	attempts int
+	backoff  time.Duration
+	random   *rand.Rand
+}
+
+type runner struct {
+	configs   map[string][]byte
+	dones     map[string]chan bool
+	events    Publisher
+	mutex     sync.Mutex
+	regulator Regulator
+	running   map[string]*Cluster
+	server    Server
+	stopping  map[*Cluster]bool
+	tracker   Tracker
+	wg        sync.WaitGroup
+}
+
+type scope struct {
+	keyspace string
+	table    string
+}
+
+type sender struct {
+	Tracker
+	queue chan interface{}
+	sinks []Sink
+}
+
 type server struct {
+	audit    io.Writer
 	callback string
 	clusters []*Cluster
+	db       Pinger
+	events   Publisher
+	last     map[string]*RepairStats
+	metrics  Metrics
+	mutex    sync.RWMutex
 	mux      *http.ServeMux
+	token    string
 	tracker  Tracker
 }
 
+type statsdSink struct {
+	conn   net.Conn
+	mutex  sync.Mutex
+	prefix string
+}
+
+type subscriber struct {
+	clusters  []string
+	events    chan *Event
+	keyspaces []string
+}
+
 type tracker struct {
 	db        DB
 	regulator Regulator
 }
+
+type tokenRange struct {
+	start *big.Int
+	end   *big.Int
+}
+
+type validator struct {
+	errors ConfigErrors
+	file   string
+	lines  map[string]int
+}
+
+type window struct {
+	days     uint64
+	start    int
+	end      int
+	location *time.Location
+}
@@ -0,0 +1,349 @@
+package cagrr
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// Error describes configuration problem with its location
+func (e *ConfigError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+	}
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+// Error joins all configuration problems one per line
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (errs ConfigErrors) Len() int {
+	return len(errs)
+}
+
+func (errs ConfigErrors) Less(i, j int) bool {
+	if errs[i].File != errs[j].File {
+		return errs[i].File < errs[j].File
+	}
+	return errs[i].Line < errs[j].Line
+}
+
+func (errs ConfigErrors) Swap(i, j int) {
+	errs[i], errs[j] = errs[j], errs[i]
+}
+
+// ValidateConfiguration checks configuration decoded strictly from file together with errors of decoder,
+// all found problems are returned. Settings which may come from other files, environment or flags are checked when configuration is merged
+func ValidateConfiguration(filename string, source []byte, config *Config, decodeErr error) ConfigErrors {
+	v := &validator{
+		file:  filename,
+		lines: locate(source),
+	}
+
+	if typeErr, ok := decodeErr.(*yaml.TypeError); ok {
+		for _, message := range typeErr.Errors {
+			v.decodeError(message)
+		}
+	} else if decodeErr != nil {
+		v.decodeError(decodeErr.Error())
+		return v.errors
+	}
+
+	v.config(config)
+	sort.Stable(v.errors)
+	return v.errors
+}
+
+// validate merged configuration: cluster names should be unique, every cluster needs address of repair service
+func (c *Config) validate() ConfigErrors {
+	var errs ConfigErrors
+	if len(c.Clusters) == 0 {
+		errs = append(errs, &ConfigError{File: strings.Join(c.Files(), ", "), Message: "no clusters configured"})
+	}
+
+	names := make(map[string]*Cluster)
+	for _, cluster := range c.Clusters {
+		v := &validator{file: cluster.source, lines: c.files[cluster.source]}
+		if first, ok := names[cluster.Name]; ok && cluster.Name != "" {
+			firstLine := (&validator{lines: c.files[first.source]}).line(first.path + ".name")
+			v.add(cluster.path+".name", "duplicate cluster name %q, first defined at %s:%d", cluster.Name, first.source, firstLine)
+		} else {
+			names[cluster.Name] = cluster
+		}
+
+		v.required(cluster.path, "host", cluster.Host)
+		switch {
+		case cluster.Port == 0:
+			v.add(cluster.path, "port is required")
+		case cluster.Port < 0 || cluster.Port > 65535:
+			v.add(cluster.path+".port", "should be between 1 and 65535")
+		}
+		errs = append(errs, v.errors...)
+	}
+	return errs
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, &ConfigError{
+		File:    v.file,
+		Line:    v.line(path),
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) config(c *Config) {
+	if c.BufferLength < 0 {
+		v.add("buffer", "should not be negative")
+	}
+	for i, sink := range c.Metrics {
+		v.sink(fmt.Sprintf("metrics[%d]", i), sink)
+	}
+	for i, cluster := range c.Clusters {
+		v.cluster(fmt.Sprintf("clusters[%d]", i), cluster)
+	}
+}
+
+func (v *validator) cluster(path string, c *Cluster) {
+	v.required(path, "name", c.Name)
+	v.durations(path, map[string]string{
+		"interval":       c.Interval,
+		"repair_timeout": c.RepairTimeout,
+		"retry_backoff":  c.RetryBackoff,
+	})
+	v.positive(path, map[string]int64{
+		"parallelism":          int64(c.Parallelism),
+		"max_repairs_per_node": int64(c.MaxRepairsPerNode),
+		"max_attempts":         int64(c.MaxAttempts),
+		"fragment_size":        c.FragmentSize,
+	})
+	if c.Crontab != "" {
+		schedule, err := ParseCron(c.Crontab)
+		switch {
+		case err != nil:
+			v.add(path+".schedule", "%s", err)
+		case schedule.Next(time.Now()).IsZero():
+			v.add(path+".schedule", "never fires")
+		}
+	}
+	v.windows(path, c.Windows)
+	v.patterns(path+".exclude_keyspaces", c.ExcludeKeyspaces)
+
+	for i, k := range c.Keyspaces {
+		v.keyspace(fmt.Sprintf("%s.keyspaces[%d]", path, i), k)
+	}
+}
+
+// decodeError converts "line N: message" error of yaml decoder, path is the deepest and the first one at that line
+func (v *validator) decodeError(message string) {
+	e := &ConfigError{File: v.file, Message: message}
+	if m := typeErrorLine.FindStringSubmatch(message); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Message = m[2]
+		for path, line := range v.lines {
+			if line == e.Line && (len(path) > len(e.Path) || len(path) == len(e.Path) && path < e.Path) {
+				e.Path = path
+			}
+		}
+	}
+	v.errors = append(v.errors, e)
+}
+
+func (v *validator) durations(path string, values map[string]string) {
+	for _, key := range sortedKeys(values) {
+		if err := checkDurations(values[key]); err != nil {
+			v.add(path+"."+key, "%s", err)
+		}
+	}
+}
+
+func (v *validator) keyspace(path string, k *Keyspace) {
+	v.required(path, "name", k.Name)
+	v.durations(path, map[string]string{
+		"interval": k.Interval,
+		"timeout":  k.Timeout,
+	})
+	v.positive(path, map[string]int64{
+		"slices":      int64(k.Slices),
+		"parallelism": int64(k.Parallelism),
+	})
+	v.windows(path, k.Windows)
+	v.patterns(path+".include_tables", k.IncludeTables)
+	v.patterns(path+".exclude_tables", k.ExcludeTables)
+
+	for i, t := range k.TableSettings {
+		tablePath := fmt.Sprintf("%s.tables[%d]", path, i)
+		v.required(tablePath, "name", t.Name)
+		v.durations(tablePath, map[string]string{
+			"interval": t.Interval,
+			"timeout":  t.Timeout,
+		})
+		v.positive(tablePath, map[string]int64{
+			"slices":           int64(t.Slices),
+			"parallelism":      int64(t.Parallelism),
+			"gc_grace_seconds": int64(t.GcGrace),
+			"size":             t.Size,
+		})
+		if t.Weight < 0 {
+			v.add(tablePath+".weight", "should not be negative")
+		}
+	}
+}
+
+// line of path or of its closest parent found in source
+func (v *validator) line(path string) int {
+	for path != "" {
+		if line, ok := v.lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+func (v *validator) patterns(path string, specs []string) {
+	for i, spec := range specs {
+		if _, err := ParsePattern(spec); err != nil {
+			v.add(fmt.Sprintf("%s[%d]", path, i), "%s", err)
+		}
+	}
+}
+
+func (v *validator) positive(path string, values map[string]int64) {
+	for _, key := range sortedKeys(values) {
+		if values[key] < 0 {
+			v.add(path+"."+key, "should not be negative")
+		}
+	}
+}
+
+func (v *validator) required(path, key, value string) {
+	if value == "" {
+		v.add(path, "%s is required", key)
+	}
+}
+
+// sink needs known type, address of receiver and known placeholders in prefix
+func (v *validator) sink(path string, s *SinkConfig) {
+	switch s.Type {
+	case "statsd", "graphite":
+	case "":
+		v.add(path, "type is required")
+	default:
+		v.add(path+".type", "should be statsd or graphite, got %q", s.Type)
+	}
+	if s.Address == "" {
+		v.add(path, "address is required")
+	} else if _, _, err := net.SplitHostPort(s.Address); err != nil {
+		v.add(path+".address", "%s", err)
+	}
+	if s.Prefix == "" {
+		return
+	}
+	found := make(map[string]bool)
+	for _, m := range placeholder.FindAllStringSubmatch(s.Prefix, -1) {
+		found[m[1]] = true
+	}
+	for _, name := range sortedKeys(found) {
+		if name != "cluster" && name != "keyspace" && name != "table" {
+			v.add(path+".prefix", "unknown placeholder {%s}", name)
+		}
+	}
+	if !found["cluster"] || !found["keyspace"] || !found["table"] {
+		v.add(path+".prefix", "should include {cluster}, {keyspace} and {table} to keep their metrics apart")
+	}
+}
+
+func (v *validator) windows(path string, specs []string) {
+	for i, spec := range specs {
+		if _, err := ParseWindow(spec); err != nil {
+			v.add(fmt.Sprintf("%s.windows[%d]", path, i), "%s", err)
+		}
+	}
+}
+
+// locate finds lines of keys and list items in yaml node tree, paths look like clusters[0].keyspaces[1].name.
+// Keys merged from anchors are located at the anchor unless they are set explicitly
+func locate(source []byte) map[string]int {
+	result := make(map[string]int)
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(source, &root); err != nil {
+		return result
+	}
+
+	var walk func(path string, node *yamlv3.Node)
+	walk = func(path string, node *yamlv3.Node) {
+		switch node.Kind {
+		case yamlv3.DocumentNode:
+			for _, child := range node.Content {
+				walk(path, child)
+			}
+		case yamlv3.AliasNode:
+			walk(path, node.Alias)
+		case yamlv3.MappingNode:
+			var merged []*yamlv3.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if key.Tag == "!!merge" {
+					merged = append(merged, value)
+					continue
+				}
+				child := key.Value
+				if path != "" {
+					child = path + "." + key.Value
+				}
+				if _, ok := result[child]; !ok {
+					result[child] = key.Line
+				}
+				walk(child, value)
+			}
+			for _, value := range merged {
+				walk(path, value)
+			}
+		case yamlv3.SequenceNode:
+			for i, item := range node.Content {
+				child := fmt.Sprintf("%s[%d]", path, i)
+				if _, ok := result[child]; !ok {
+					result[child] = item.Line
+				}
+				walk(child, item)
+			}
+		}
+	}
+	walk("", &root)
+	return result
+}
+
+func sortedKeys(values interface{}) []string {
+	keys := reflect.ValueOf(values).MapKeys()
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key.String())
+	}
+	sort.Strings(result)
+	return result
+}
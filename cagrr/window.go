@@ -0,0 +1,140 @@
+package cagrr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseWindow parses maintenance window, either cron expression matching allowed minutes
+// or days with time range and optional time zone, e.g. "Mon-Fri 22:00-06:00 Europe/Moscow"
+func ParseWindow(spec string) (Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Empty window")
+	}
+	if strings.HasPrefix(fields[0], "@") || len(fields) >= 5 {
+		return ParseCron(spec)
+	}
+
+	w := &window{location: time.Local}
+	if !strings.Contains(fields[0], ":") {
+		days, err := parseDays(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid window %q: %s", spec, err)
+		}
+		w.days = days
+		fields = fields[1:]
+	} else {
+		w.days = 1<<7 - 1
+	}
+
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("Invalid window %q: time range expected", spec)
+	}
+	bounds := strings.SplitN(fields[0], "-", 2)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("Invalid window %q: time range expected", spec)
+	}
+	var err error
+	if w.start, err = parseClock(bounds[0]); err != nil {
+		return nil, fmt.Errorf("Invalid window %q: %s", spec, err)
+	}
+	if w.end, err = parseClock(bounds[1]); err != nil {
+		return nil, fmt.Errorf("Invalid window %q: %s", spec, err)
+	}
+	if len(fields) == 2 {
+		if w.location, err = time.LoadLocation(fields[1]); err != nil {
+			return nil, fmt.Errorf("Invalid window %q: %s", spec, err)
+		}
+	}
+	return w, nil
+}
+
+// ParseWindows parses list of maintenance windows
+func ParseWindows(specs []string) ([]Window, error) {
+	var result []Window
+	for _, spec := range specs {
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+// IsOpen checks that time is inside of any window, empty list allows any time
+func IsOpen(windows []Window, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains checks that time is inside of window. Window crossing midnight belongs to the day it starts
+func (w *window) Contains(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+
+	if w.start <= w.end {
+		return has(w.days, today) && minute >= w.start && minute < w.end
+	}
+	return (has(w.days, today) && minute >= w.start) ||
+		(has(w.days, yesterday) && minute < w.end)
+}
+
+// Contains nothing: closed window is used instead of invalid ones
+func (w closedWindow) Contains(t time.Time) bool {
+	return false
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("bad time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseDays(expr string) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(expr, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := parseDay(bounds[0])
+		if err != nil {
+			return 0, err
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = parseDay(bounds[1]); err != nil {
+				return 0, err
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			result |= 1 << uint(d)
+			if d == end {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func parseDay(s string) (int, error) {
+	day, ok := dayNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("bad day %q", s)
+	}
+	return day, nil
+}
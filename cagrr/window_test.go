@@ -0,0 +1,87 @@
+package cagrr_test
+
+import (
+	"time"
+
+	. "github.com/skbkontur/cagrr/cagrr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Window", func() {
+	at := func(value string) time.Time {
+		t, _ := time.Parse("Mon 2006-01-02 15:04", value)
+		return t
+	}
+
+	Context("nightly window", func() {
+		var window Window
+		BeforeEach(func() {
+			var err error
+			window, err = ParseWindow("Mon-Fri 22:00-06:00 UTC")
+			Expect(err).To(BeNil())
+		})
+
+		It("should contain weekday night", func() {
+			Expect(window.Contains(at("Tue 2017-03-07 23:30"))).To(BeTrue())
+		})
+		It("should contain morning after weekday", func() {
+			Expect(window.Contains(at("Sat 2017-03-11 05:59"))).To(BeTrue())
+		})
+		It("shouldn't contain daytime", func() {
+			Expect(window.Contains(at("Tue 2017-03-07 12:00"))).To(BeFalse())
+		})
+		It("shouldn't contain weekend night", func() {
+			Expect(window.Contains(at("Sat 2017-03-11 23:00"))).To(BeFalse())
+		})
+		It("shouldn't contain morning after weekend", func() {
+			Expect(window.Contains(at("Mon 2017-03-06 01:00"))).To(BeFalse())
+		})
+	})
+
+	Context("daily window", func() {
+		It("should apply to every day", func() {
+			window, err := ParseWindow("01:00-03:00 UTC")
+			Expect(err).To(BeNil())
+			Expect(window.Contains(at("Sun 2017-03-12 02:00"))).To(BeTrue())
+			Expect(window.Contains(at("Sun 2017-03-12 03:00"))).To(BeFalse())
+		})
+	})
+
+	Context("cron window", func() {
+		It("should contain matching minutes", func() {
+			window, err := ParseWindow("* 0-5 * * Sat,Sun UTC")
+			Expect(err).To(BeNil())
+			Expect(window.Contains(at("Sun 2017-03-12 04:59"))).To(BeTrue())
+			Expect(window.Contains(at("Mon 2017-03-13 04:59"))).To(BeFalse())
+		})
+	})
+
+	Context("invalid window", func() {
+		It("should fail on unknown day", func() {
+			_, err := ParseWindow("Mon-Frx 22:00-06:00")
+			Expect(err).NotTo(BeNil())
+		})
+		It("should fail on bad time", func() {
+			_, err := ParseWindow("Mon-Fri 22:00-30:00")
+			Expect(err).NotTo(BeNil())
+		})
+		It("should fail on unknown time zone", func() {
+			_, err := ParseWindow("22:00-06:00 Nowhere/Town")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("list of windows", func() {
+		It("should be open without windows", func() {
+			Expect(IsOpen(nil, time.Now())).To(BeTrue())
+		})
+		It("should be open inside of any window", func() {
+			windows, err := ParseWindows([]string{"Sat 10:00-12:00 UTC", "Sun 10:00-12:00 UTC"})
+			Expect(err).To(BeNil())
+			Expect(IsOpen(windows, at("Sun 2017-03-12 11:00"))).To(BeTrue())
+			Expect(IsOpen(windows, at("Mon 2017-03-13 11:00"))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+// openTracker connects to configured database, database should be closed by caller
+func openTracker(config *cagrr.Config) (cagrr.Tracker, cagrr.DB) {
+	db := cagrr.NewConsulDb(config.ConsulHost)
+	regulator := cagrr.NewRegulator(config.BufferLength)
+	return cagrr.NewTracker(db, regulator), db
+}
+
+// selectClusters finds clusters by name, all clusters are returned when no names given
+func selectClusters(config *cagrr.Config, names []string) ([]*cagrr.Cluster, error) {
+	if len(names) == 0 {
+		return config.Clusters, nil
+	}
+
+	var result []*cagrr.Cluster
+	for _, name := range names {
+		cluster := findCluster(config, name)
+		if cluster == nil {
+			return nil, fmt.Errorf("Cluster %s is not configured", name)
+		}
+		result = append(result, cluster)
+	}
+	return result, nil
+}
+
+func findCluster(config *cagrr.Config, name string) *cagrr.Cluster {
+	for _, c := range config.Clusters {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+type configCommand struct {
+	Show     struct{} `command:"show" description:"Print effective configuration and source of every value"`
+	Validate struct{} `command:"validate" description:"Validate configuration file and print all problems found"`
+}
+
+func (c *configCommand) run(command *flags.Command) int {
+	if command == nil {
+		logger.Error("Config command requires subcommand, see cagrr config --help")
+		return 1
+	}
+	switch command.Name {
+	case "show":
+		return show()
+	case "validate":
+		return validate()
+	}
+	return 0
+}
+
+func show() int {
+	config, err := loadConfiguration()
+	if config == nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "SETTING\tVALUE\tSOURCE")
+	for _, s := range config.Settings() {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", s.Path, s.Value, s.Source)
+	}
+	writer.Flush()
+
+	if err != nil {
+		fmt.Fprintln(out)
+		return printErrors(err)
+	}
+	return 0
+}
+
+func printErrors(err error) int {
+	if errs, ok := err.(cagrr.ConfigErrors); ok {
+		for _, e := range errs {
+			fmt.Fprintln(out, e)
+		}
+		return 1
+	}
+	fmt.Fprintln(out, err)
+	return 1
+}
+
+func validate() int {
+	_, err := loadConfiguration()
+	if err != nil {
+		return printErrors(err)
+	}
+	fmt.Fprintf(out, "%s: configuration is valid\n", opts.ConfigFile)
+	return 0
+}
@@ -4,6 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	nethttp "net/http"
 	_ "net/http/pprof"
@@ -15,11 +21,24 @@ import (
 var version = "devel"
 
 var opts struct {
-	Verbosity     string `short:"v" long:"verbosity" default:"debug" description:"Verbosity of tool, possible values are: panic, fatal, error, waring, debug"`
-	ListenAddress string `short:"a" long:"listen" default:"localhost:8888" description:"host:port string of listen address for repair callbacks"`
-	LogFile       string `short:"l" long:"log" default:"stdout" description:"Log file name"`
-	ConfigFile    string `short:"c" long:"config" default:"/etc/cagrr/config.yml" description:"Configuration file name"`
-	Version       bool   `long:"version" description:"Show version info and exit"`
+	Verbosity       string        `short:"v" long:"verbosity" default:"debug" description:"Verbosity of tool, possible values are: panic, fatal, error, waring, debug"`
+	ListenAddress   string        `short:"a" long:"listen" default:"localhost:8888" description:"host:port string of listen address for repair callbacks"`
+	APIToken        string        `long:"api-token" env:"CAGRR_API_TOKEN" description:"Bearer token of control API, control API is disabled without it"`
+	AuditLog        string        `long:"audit-log" description:"File to append control API actions to, they are logged anyway"`
+	LogFile         string        `short:"l" long:"log" default:"stdout" description:"Log file name"`
+	ConfigFile      string        `short:"c" long:"config" default:"/etc/cagrr/config.yml" description:"Configuration file or directory of *.yml files"`
+	ConsulHost      string        `long:"consul-host" description:"Consul address, overrides configuration file and CAGRR_CONSUL_HOST"`
+	BufferLength    int           `long:"buffer" description:"Length of repair duration buffer, overrides configuration file and CAGRR_BUFFER"`
+	ClusterHosts    []string      `long:"cluster-host" value-name:"NAME=HOST" description:"Repair service host of cluster, overrides configuration file and CAGRR_CLUSTER_<NAME>_HOST"`
+	ClusterPorts    []string      `long:"cluster-port" value-name:"NAME=PORT" description:"Repair service port of cluster, overrides configuration file and CAGRR_CLUSTER_<NAME>_PORT"`
+	ShutdownTimeout time.Duration `long:"shutdown-timeout" default:"1m" description:"Time to wait for running repairs on shutdown"`
+	WatchInterval   time.Duration `long:"config-watch" default:"0s" description:"Interval of configuration files change checks, 0 disables watching"`
+	Version         bool          `long:"version" description:"Show version info and exit"`
+	Config          configCommand `command:"config" description:"Check and show configuration"`
+	Plan            planCommand   `command:"plan" description:"Print repair plan without running it"`
+	Status          statusCommand `command:"status" description:"Print stored repair progress"`
+	Repair          repairCommand `command:"repair" description:"Repair keyspace or table once right now"`
+	Reset           resetCommand  `command:"reset" description:"Forget stored repair progress to repair again"`
 }
 
 // in/out streams
@@ -34,42 +53,159 @@ var (
 )
 
 func main() {
-	config, err := cagrr.ReadConfiguration(opts.ConfigFile)
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+	checkVersion()
+
+	logger = cagrr.NewLogger(opts.Verbosity, opts.LogFile)
+
+	if parser.Active != nil {
+		os.Exit(runCommand(parser.Active))
+	}
+	serve()
+}
+
+// overrides of configuration given by flags
+func overrides() ([]cagrr.Override, error) {
+	var result []cagrr.Override
+	if opts.ConsulHost != "" {
+		result = append(result, cagrr.Override{Key: "consul_host", Value: opts.ConsulHost, Source: "flag --consul-host"})
+	}
+	if opts.BufferLength != 0 {
+		result = append(result, cagrr.Override{Key: "buffer", Value: strconv.Itoa(opts.BufferLength), Source: "flag --buffer"})
+	}
+	for flag, specs := range map[string][]string{"host": opts.ClusterHosts, "port": opts.ClusterPorts} {
+		for _, spec := range specs {
+			parts := strings.SplitN(spec, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("Cluster %s should look like NAME=VALUE, got %q", flag, spec)
+			}
+			result = append(result, cagrr.Override{
+				Key:    "cluster." + parts[0] + "." + flag,
+				Value:  parts[1],
+				Source: "flag --cluster-" + flag,
+			})
+		}
+	}
+	return result, nil
+}
+
+func readConfiguration() *cagrr.Config {
+	config, err := loadConfiguration()
+	if errs, ok := err.(cagrr.ConfigErrors); ok {
+		for _, e := range errs {
+			logger.WithError(e).Error("Invalid configuration")
+		}
+		os.Exit(1)
+	}
 	if err != nil {
 		logger.WithError(err).Error("Error when reading configuration")
 		os.Exit(1)
 	}
+	return config
+}
+
+func loadConfiguration() (*cagrr.Config, error) {
+	flagOverrides, err := overrides()
+	if err != nil {
+		return nil, err
+	}
+	return cagrr.ReadConfiguration(opts.ConfigFile, flagOverrides...)
+}
+
+func runCommand(command *flags.Command) int {
+	switch command.Name {
+	case "config":
+		return opts.Config.run(command.Active)
+	case "plan":
+		return opts.Plan.run(readConfiguration())
+	case "repair":
+		return opts.Repair.run(readConfiguration())
+	case "reset":
+		return opts.Reset.run(readConfiguration())
+	case "status":
+		return opts.Status.run(readConfiguration())
+	}
+	return 0
+}
+
+func serve() {
+	config := readConfiguration()
 
 	consul := cagrr.NewConsulDb(config.ConsulHost)
 	//redis := cagrr.NewRedisDb("localhost:6379")
 	database := consul
 	regulator := cagrr.NewRegulator(config.BufferLength)
-	tracker := cagrr.NewTracker(consul, regulator)
-	server := cagrr.NewServer(tracker)
+
+	var sinks []cagrr.Sink
+	for _, sinkConfig := range config.Metrics {
+		sink, err := cagrr.NewSink(sinkConfig)
+		if err != nil {
+			logger.WithError(err).Error("Error when opening metrics sink")
+			os.Exit(1)
+		}
+		defer sink.Close()
+		sinks = append(sinks, sink)
+	}
+
+	metrics := cagrr.NewMetrics(cagrr.NewSender(cagrr.NewTracker(consul, regulator), sinks...), regulator)
+	server := cagrr.NewServer(metrics, config.Clusters).AuthorizeBy(opts.APIToken).MeasureBy(metrics).ProbeWith(database)
+	events := cagrr.NewPublisher()
+	server.PublishTo(events)
+	runner := cagrr.NewRunner(metrics, regulator, server, events)
 
 	defer database.Close()
 
-	server.ServeAt(opts.ListenAddress)
+	if opts.AuditLog != "" {
+		audit, err := os.OpenFile(opts.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			logger.WithError(err).Error("Error when opening audit log")
+			os.Exit(1)
+		}
+		defer audit.Close()
+		server.AuditTo(audit)
+	}
 
-	done := make(chan bool)
-	for _, cluster := range config.Clusters {
-		go cluster.
-			RegulateWith(regulator).
-			TrackIn(tracker).
-			Until(done).
-			Schedule()
+	if opts.Verbosity == "debug" {
+		go startProfiling()
 	}
-	<-done
-}
 
-func init() {
-	flags.Parse(&opts)
-	checkVersion()
+	server.ServeAt(opts.ListenAddress)
+	runner.Run(config)
 
-	logger = cagrr.NewLogger(opts.Verbosity, opts.LogFile)
+	waitForSignal(config, runner)
+	shutdown(runner)
+}
 
-	if opts.Verbosity == "debug" {
-		go startProfiling()
+// reload configuration and apply it to running clusters, current configuration is kept on errors
+func reload(current *cagrr.Config, runner cagrr.Runner) *cagrr.Config {
+	config, err := loadConfiguration()
+	if err != nil {
+		logger.WithError(err).Error("Error when reloading configuration, keeping current one")
+		return current
+	}
+	if config.ConsulHost != current.ConsulHost || config.BufferLength != current.BufferLength {
+		logger.Warn("Changes of consul host and buffer length require restart")
+	}
+	if !reflect.DeepEqual(config.Metrics, current.Metrics) {
+		logger.Warn("Changes of metrics sinks require restart")
+	}
+	runner.Run(config)
+	logger.Info("Configuration reloaded")
+	return config
+}
+
+func shutdown(runner cagrr.Runner) {
+	if runner.Stop(opts.ShutdownTimeout) {
+		logger.Info("All clusters stopped")
+	} else {
+		logger.Warn(fmt.Sprintf("Running repairs weren't completed in %s", opts.ShutdownTimeout))
 	}
 }
 
@@ -83,3 +219,53 @@ func checkVersion() {
 		os.Exit(0)
 	}
 }
+
+func waitForSignal(config *cagrr.Config, runner cagrr.Runner) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	var ticks <-chan time.Time
+	if opts.WatchInterval > 0 {
+		ticker := time.NewTicker(opts.WatchInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	last := modified(config)
+
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				logger.Info(fmt.Sprintf("Signal %s received, reloading configuration", sig))
+				config = reload(config, runner)
+				last = modified(config)
+				continue
+			}
+			logger.Info(fmt.Sprintf("Signal %s received, stopping", sig))
+			return
+		case <-ticks:
+			current := modified(config)
+			if current.IsZero() || current.Equal(last) {
+				continue
+			}
+			logger.Info("Configuration files changed, reloading")
+			config = reload(config, runner)
+			last = current
+		}
+	}
+}
+
+// modified returns the latest modification time of configuration files and directory
+func modified(config *cagrr.Config) time.Time {
+	var result time.Time
+	for _, file := range append(config.Files(), opts.ConfigFile) {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(result) {
+			result = info.ModTime()
+		}
+	}
+	return result
+}
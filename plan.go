@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+// planJSON is plan with estimate as duration string
+type planJSON struct {
+	*cagrr.Plan
+	Estimate string `json:"estimate"`
+}
+
+type planCommand struct {
+	Format string `short:"f" long:"format" default:"table" choice:"table" choice:"json" choice:"csv" description:"Output format"`
+	Args   struct {
+		Clusters []string `positional-arg-name:"cluster" description:"Names of clusters to plan, all clusters by default"`
+	} `positional-args:"yes"`
+}
+
+func (p *planCommand) run(config *cagrr.Config) int {
+	clusters, err := selectClusters(config, p.Args.Clusters)
+	if err != nil {
+		logger.WithError(err).Error("Error when selecting clusters")
+		return 1
+	}
+
+	tracker, db := openTracker(config)
+	defer db.Close()
+
+	var plans []*cagrr.Plan
+	for _, c := range clusters {
+		c.TrackIn(tracker)
+		plans = append(plans, c.Plan())
+	}
+
+	switch p.Format {
+	case "json":
+		err = printPlanJSON(out, plans)
+	case "csv":
+		err = printPlanCSV(out, plans)
+	default:
+		err = printPlanTable(out, plans)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Error when printing plan")
+		return 1
+	}
+	return 0
+}
+
+// printPlanCSV writes a row per fragment followed by summary row of cluster with its totals and estimate
+func printPlanCSV(w io.Writer, plans []*cagrr.Plan) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"cluster", "keyspace", "table", "id", "endpoint", "start", "end", "skip", "total", "skipped", "repair", "estimate"})
+	for _, plan := range plans {
+		for _, i := range plan.Items {
+			writer.Write([]string{
+				plan.Cluster,
+				i.Keyspace,
+				i.Table,
+				strconv.Itoa(i.ID),
+				i.Endpoint,
+				i.Start,
+				i.End,
+				strconv.FormatBool(i.Skip),
+				"", "", "", "",
+			})
+		}
+		writer.Write([]string{
+			plan.Cluster,
+			"", "", "", "", "", "", "",
+			strconv.Itoa(plan.Total),
+			strconv.Itoa(plan.Skipped),
+			strconv.Itoa(plan.Total - plan.Skipped),
+			plan.Estimate.String(),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func printPlanJSON(w io.Writer, plans []*cagrr.Plan) error {
+	result := make([]*planJSON, 0, len(plans))
+	for _, plan := range plans {
+		result = append(result, &planJSON{plan, plan.Estimate.String()})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func printPlanTable(w io.Writer, plans []*cagrr.Plan) error {
+	writer := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, plan := range plans {
+		fmt.Fprintf(writer, "Cluster %s\n", plan.Cluster)
+		fmt.Fprintln(writer, "KEYSPACE\tTABLE\tID\tENDPOINT\tSTART\tEND\tACTION")
+		for _, i := range plan.Items {
+			action := "repair"
+			if i.Skip {
+				action = "skip"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", i.Keyspace, i.Table, i.ID, i.Endpoint, i.Start, i.End, action)
+		}
+		fmt.Fprintf(writer, "Total: %d, skipped: %d, to repair: %d, estimated duration: %s\n\n",
+			plan.Total, plan.Skipped, plan.Total-plan.Skipped, plan.Estimate)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+var testPlans = []*cagrr.Plan{{
+	Cluster:  "c",
+	Total:    2,
+	Skipped:  1,
+	Estimate: 90 * time.Second,
+	Items: []*cagrr.PlanItem{
+		{Keyspace: "k", Table: "t", ID: 1, Endpoint: "10.0.0.1", Start: "0", End: "100", Skip: true},
+		{Keyspace: "k", Table: "t", ID: 2, Endpoint: "10.0.0.2", Start: "100", End: "200"},
+	},
+}}
+
+func TestPlanCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printPlanCSV(&buf, testPlans); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"cluster,keyspace,table,id,endpoint,start,end,skip,total,skipped,repair,estimate",
+		"c,k,t,1,10.0.0.1,0,100,true,,,,",
+		"c,k,t,2,10.0.0.2,100,200,false,,,,",
+		"c,,,,,,,,2,1,1,1m30s",
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("It should write fragments and cluster totals (%q expected, %q returned)", expected, lines)
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printPlanJSON(&buf, testPlans); err != nil {
+		t.Fatal(err)
+	}
+
+	var plans []struct {
+		Cluster  string            `json:"cluster"`
+		Total    int               `json:"total"`
+		Skipped  int               `json:"skipped"`
+		Estimate string            `json:"estimate"`
+		Items    []*cagrr.PlanItem `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &plans); err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || plans[0].Estimate != "1m30s" || plans[0].Total != 2 || plans[0].Skipped != 1 || len(plans[0].Items) != 2 {
+		t.Errorf("It should encode plan with estimate as duration, %s returned", buf.String())
+	}
+}
+
+func TestPlanTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printPlanTable(&buf, testPlans); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"Cluster c", "skip", "repair", "Total: 2, skipped: 1, to repair: 1, estimated duration: 1m30s"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("It should contain %q, %s returned", expected, output)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+type repairCommand struct {
+	Cluster  string `short:"c" long:"cluster" required:"yes" description:"Name of cluster"`
+	Keyspace string `short:"k" long:"keyspace" required:"yes" description:"Name of keyspace"`
+	Table    string `short:"t" long:"table" description:"Name of table, all tables of keyspace by default"`
+	Range    string `long:"range" description:"Repair only fragments overlapping start:end token range"`
+	Listen   string `long:"callback-listen" default:"localhost:0" description:"host:port to receive repair callbacks at, ephemeral port by default not to clash with running daemon"`
+	Host     string `long:"callback-host" description:"Host repair service reaches callbacks at, required when listening on all interfaces"`
+}
+
+func (r *repairCommand) run(config *cagrr.Config) int {
+	cluster := findCluster(config, r.Cluster)
+	if cluster == nil {
+		logger.Error(fmt.Sprintf("Cluster %s is not configured", r.Cluster))
+		return 1
+	}
+
+	tracker, db := openTracker(config)
+	defer db.Close()
+
+	listener, err := net.Listen("tcp", r.Listen)
+	if err != nil {
+		logger.WithError(err).Error("Error when listening for repair callbacks")
+		return 1
+	}
+	defer listener.Close()
+	callback, err := callbackURL(listener.Addr(), r.Host)
+	if err != nil {
+		logger.WithError(err).Error("Error when building repair callback")
+		return 1
+	}
+	server := cagrr.NewServer(tracker, []*cagrr.Cluster{cluster})
+	go http.Serve(listener, server)
+	cluster.TrackIn(tracker).CallbackAt(callback)
+
+	completed := 0
+	progress := func(repair *cagrr.Repair, event string) {
+		if event == "completed" {
+			completed++
+		}
+		fmt.Fprintf(out, "%s  %s.%s #%d (%s, %s] on %s: %s, %d completed\n",
+			time.Now().Format("15:04:05"), repair.Keyspace, repair.Table, repair.ID,
+			repair.Start, repair.End, repair.Endpoint, event, completed)
+	}
+
+	failed, err := cluster.RepairOnce(r.Keyspace, r.Table, r.Range, progress)
+	if err != nil {
+		logger.WithError(err).Error("Error when starting repair")
+		return 1
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(out, "Repair finished, %d completed, %d failed:\n", completed, len(failed))
+		for _, repair := range failed {
+			fmt.Fprintf(out, "  %s\n", repair.Key())
+		}
+		return 1
+	}
+	fmt.Fprintf(out, "Repair finished, %d completed\n", completed)
+	return 0
+}
+
+// callbackURL of listener advertised to repair service, wildcard listen address needs explicit host
+func callbackURL(addr net.Addr, advertised string) (string, error) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", err
+	}
+	if advertised != "" {
+		host = advertised
+	} else if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return "", fmt.Errorf("Repair service can't reach callbacks at %s, set --callback-host", addr)
+	}
+	return fmt.Sprintf("http://%s/status", net.JoinHostPort(host, port)), nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCallbackURL(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 9000}
+	url, err := callbackURL(addr, "")
+	if err != nil || url != "http://127.0.0.1:9000/status" {
+		t.Errorf("It should use listen address, %q and %v returned", url, err)
+	}
+}
+
+func TestCallbackURLOfWildcard(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv6unspecified, Port: 9000}
+	if _, err := callbackURL(addr, ""); err == nil {
+		t.Error("It should reject wildcard listen address without callback host")
+	}
+
+	url, err := callbackURL(addr, "repairer.local")
+	if err != nil || url != "http://repairer.local:9000/status" {
+		t.Errorf("It should advertise callback host, %q and %v returned", url, err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+type resetCommand struct {
+	Fragment int `long:"fragment" default:"-1" description:"ID of single fragment to reset, table is required"`
+	Args     struct {
+		Cluster  string `positional-arg-name:"cluster" required:"yes" description:"Name of cluster"`
+		Keyspace string `positional-arg-name:"keyspace" description:"Name of keyspace"`
+		Table    string `positional-arg-name:"table" description:"Name of table"`
+	} `positional-args:"yes"`
+}
+
+func (r *resetCommand) run(config *cagrr.Config) int {
+	keys, err := r.keys()
+	if err != nil {
+		logger.WithError(err).Error("Wrong reset arguments")
+		return 1
+	}
+	if findCluster(config, r.Args.Cluster) == nil {
+		logger.Error("Cluster " + r.Args.Cluster + " is not configured")
+		return 1
+	}
+
+	tracker, db := openTracker(config)
+	defer db.Close()
+
+	if err := tracker.Reset(keys...); err != nil {
+		logger.WithError(err).Error("Error when resetting repair progress")
+		return 1
+	}
+	logger.WithFields(r.Args).Info("Repair progress reset")
+	return 0
+}
+
+func (r *resetCommand) keys() ([]string, error) {
+	keys := []string{r.Args.Cluster}
+	if r.Args.Keyspace != "" {
+		keys = append(keys, r.Args.Keyspace)
+	}
+	if r.Args.Table != "" {
+		keys = append(keys, r.Args.Table)
+	}
+	if r.Fragment >= 0 {
+		if r.Args.Table == "" {
+			return nil, errors.New("fragment requires keyspace and table")
+		}
+		keys = append(keys, strconv.Itoa(r.Fragment))
+	}
+	return keys, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+type statusCommand struct {
+	JSON  bool          `long:"json" description:"Print status as JSON"`
+	Watch time.Duration `short:"w" long:"watch" description:"Refresh status periodically with given interval"`
+	Args  struct {
+		Cluster  string `positional-arg-name:"cluster" description:"Name of cluster, all clusters by default"`
+		Keyspace string `positional-arg-name:"keyspace" description:"Name of keyspace"`
+		Table    string `positional-arg-name:"table" description:"Name of table"`
+	} `positional-args:"yes"`
+}
+
+func (s *statusCommand) run(config *cagrr.Config) int {
+	var names []string
+	if s.Args.Cluster != "" {
+		names = append(names, s.Args.Cluster)
+	}
+	clusters, err := selectClusters(config, names)
+	if err != nil {
+		logger.WithError(err).Error("Error when selecting clusters")
+		return 1
+	}
+
+	tracker, db := openTracker(config)
+	defer db.Close()
+	for _, c := range clusters {
+		c.TrackIn(tracker)
+	}
+
+	for {
+		var statuses []*cagrr.Status
+		for _, c := range clusters {
+			statuses = append(statuses, c.Status(s.Args.Keyspace, s.Args.Table)...)
+		}
+
+		if s.JSON {
+			err = printStatusJSON(out, statuses)
+		} else {
+			if s.Watch > 0 {
+				fmt.Fprint(out, clearScreen)
+			}
+			err = printStatusTable(out, statuses)
+		}
+		if err != nil {
+			logger.WithError(err).Error("Error when printing status")
+			return 1
+		}
+
+		if s.Watch <= 0 {
+			return 0
+		}
+		time.Sleep(s.Watch)
+	}
+}
+
+func printStatusJSON(w io.Writer, statuses []*cagrr.Status) error {
+	return json.NewEncoder(w).Encode(statuses)
+}
+
+func printStatusTable(w io.Writer, statuses []*cagrr.Status) error {
+	writer := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "CLUSTER\tKEYSPACE\tTABLE\tPERCENT\tCOMPLETED\tERRORS\tAVERAGE\tESTIMATE\tFINISHED\tSTATE\tSOURCE")
+	for _, s := range statuses {
+		finished := "never"
+		if !s.Finished.IsZero() {
+			finished = s.Finished.Format("2006-01-02 15:04:05")
+		}
+		state := "ok"
+		if s.Stale {
+			state = fmt.Sprintf("stale (interval %s)", s.Interval)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%.1f%%\t%d/%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			s.Cluster, s.Keyspace, s.Table, s.Percent, s.Completed, s.Total, s.Errors, s.Average, s.Estimate, finished, state, s.Source)
+	}
+	return writer.Flush()
+}
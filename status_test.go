@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/skbkontur/cagrr/cagrr"
+)
+
+// parseArgs parses command line into options, callers restore them
+func parseArgs(args ...string) error {
+	parser := flags.NewParser(&opts, flags.None)
+	parser.SubcommandsOptional = true
+	_, err := parser.ParseArgs(args)
+	return err
+}
+
+func TestStatusWatch(t *testing.T) {
+	saved := opts
+	defer func() { opts = saved }()
+
+	if err := parseArgs("status", "--watch", "10s", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Status.Watch != 10*time.Second || opts.Status.Args.Cluster != "c" || opts.WatchInterval != 0 {
+		t.Errorf("It should parse watch interval of status and cluster, %s and %q parsed", opts.Status.Watch, opts.Status.Args.Cluster)
+	}
+}
+
+func TestStatusWatchRequiresValue(t *testing.T) {
+	saved := opts
+	defer func() { opts = saved }()
+
+	if err := parseArgs("status", "--watch"); err == nil {
+		t.Error("It should require watch interval")
+	}
+}
+
+func TestStatusKeepsConfigWatch(t *testing.T) {
+	saved := opts
+	defer func() { opts = saved }()
+
+	if err := parseArgs("--config-watch", "1m", "status"); err != nil {
+		t.Fatal(err)
+	}
+	if opts.WatchInterval != time.Minute || opts.Status.Watch != 0 {
+		t.Errorf("It should keep --config-watch for configuration, %s and %s parsed", opts.WatchInterval, opts.Status.Watch)
+	}
+}
+
+var testStatuses = []*cagrr.Status{
+	{Cluster: "c", Percent: 50, Completed: 1, Total: 2, Interval: time.Hour, Stale: true, Source: "config.yml"},
+	{Cluster: "c", Keyspace: "k", Percent: 100, Completed: 2, Total: 2, Finished: time.Date(2017, 3, 7, 10, 15, 0, 0, time.Local), Interval: time.Hour},
+}
+
+func TestStatusJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printStatusJSON(&buf, testStatuses); err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []*cagrr.Status
+	if err := json.Unmarshal(buf.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[1].Keyspace != "k" || !statuses[0].Stale {
+		t.Errorf("It should encode statuses, %s returned", buf.String())
+	}
+}
+
+func TestStatusTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printStatusTable(&buf, testStatuses); err != nil {
+		t.Fatal(err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"CLUSTER", "50.0%", "1/2", "never", "stale (interval 1h0m0s)", "config.yml", "2017-03-07 10:15:00"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("It should contain %q, %s returned", expected, output)
+		}
+	}
+}